@@ -0,0 +1,240 @@
+package main
+
+import (
+	"CourseTool/logging"
+	"CourseTool/sdtbu"
+	"CourseTool/wxpush"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// accountsFile 是 ACCOUNTS_FILE 的內容，一個進程可以同時為多位同學推送課程
+type accountsFile struct {
+	Accounts []accountConfig `json:"accounts"`
+}
+
+// accountConfig 是 ACCOUNTS_FILE 中的一個帳號
+type accountConfig struct {
+	Name             string   `json:"name"`                       // 顯示名稱，用於日誌與 /account 切換，為空時使用學號
+	Username         string   `json:"username"`                   // 智慧山商學號
+	Password         string   `json:"password"`                   // 智慧山商密碼
+	OpenIDs          []string `json:"openIds"`                    // 接收推送的微信 OpenID，可以有多個
+	TemplateID       string   `json:"templateId,omitempty"`       // 課程提醒模板 ID，為空時使用 WXPUSH_COURSE_TEMPLATE_ID
+	NoticeTemplateID string   `json:"noticeTemplateId,omitempty"` // 成績與課表變更的通知模板 ID，為空時使用 WXPUSH_NOTICE_TEMPLATE_ID
+	PushTimes        string   `json:"pushTimes,omitempty"`        // 推送時間，格式與 PUSH_TIME_TABLE 相同，為空時使用 PUSH_TIME_TABLE
+	SessionFile      string   `json:"sessionFile,omitempty"`      // 會話文件路徑，為空時使用 cache/session-<學號>.bin
+}
+
+// account 是一個學生帳號，擁有獨立的門戶會話、課表緩存、登入暫停狀態與推送排程，
+// 一個帳號登入失敗或任務崩潰不會影響其他帳號
+type account struct {
+	name       string
+	recipients []wxpush.Recipient // 為空時只在控制台打印推送內容
+	pushTimes  []PushTime
+	prefixLogs bool         // 多帳號時日誌帶有 account 屬性，以便區分多個帳號
+	logger     *slog.Logger // 該帳號的日誌輸出
+
+	sessionMu   sync.Mutex   // 保護會話與以下登入資訊
+	session     sdtbu.Portal // 長期複用的門戶會話，避免每次推送都執行完整的 CAS 登入
+	username    string
+	password    string
+	sessionFile string
+
+	timetable *timetableStore
+	guard     *loginGuard
+	scheduler *SchedulerStatus
+	gradesMu  sync.Mutex // 保證同一時間只有一次成績檢查，避免排程器與 /grades 同時更新快照而重複推送
+	examsMu   sync.Mutex // 保護保存的考試安排，避免刷新考試安排時覆蓋剛記錄的已發送提醒
+
+	pushFunc func(ctx context.Context, now time.Time) // 排程器觸發時執行的推送，為 nil 時推送下一節課，測試中替換
+}
+
+// accounts 是本進程服務的全部帳號，啟動時由 loadAccounts 載入
+var accounts []*account
+
+// loadAccounts 載入要服務的帳號。設定了 ACCOUNTS_FILE 時從文件讀取多個帳號，
+// 否則使用 SDTBU_USERNAME、SDTBU_PASSWORD、WXPUSH_OPEN_ID 與 PUSH_TIME_TABLE 組成單個帳號。
+func loadAccounts() ([]*account, error) {
+	path := os.Getenv("ACCOUNTS_FILE")
+	if path == "" {
+		config := accountConfig{
+			Username:    os.Getenv("SDTBU_USERNAME"),
+			Password:    os.Getenv("SDTBU_PASSWORD"),
+			OpenIDs:     splitList(os.Getenv("WXPUSH_OPEN_ID")),
+			PushTimes:   os.Getenv("PUSH_TIME_TABLE"),
+			SessionFile: os.Getenv("SESSION_FILE"),
+		}
+		acct, err := newAccount(config, false)
+		if err != nil {
+			return nil, err
+		}
+		return []*account{acct}, nil
+	}
+
+	configs, err := readAccountsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	result := make([]*account, 0, len(configs))
+	for _, config := range configs {
+		acct, err := newAccount(config, true)
+		if err != nil {
+			return nil, fmt.Errorf("ACCOUNTS_FILE 中的帳號 '%s' 無效: %w", config.displayName(), err)
+		}
+		if seen[acct.name] {
+			return nil, fmt.Errorf("ACCOUNTS_FILE 中的帳號名稱 '%s' 重複", acct.name)
+		}
+		seen[acct.name] = true
+		result = append(result, acct)
+	}
+	logger().Info("已載入帳號文件", "path", path, "accounts", len(result))
+	return result, nil
+}
+
+// readAccountsFile 讀取並解析 ACCOUNTS_FILE
+func readAccountsFile(path string) ([]accountConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取帳號文件 %s 失敗: %w", path, err)
+	}
+	var file accountsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析帳號文件 %s 失敗: %w", path, err)
+	}
+	if len(file.Accounts) == 0 {
+		return nil, fmt.Errorf("帳號文件 %s 中沒有任何帳號", path)
+	}
+	return file.Accounts, nil
+}
+
+// displayName 返回帳號的顯示名稱，未設定時使用學號
+func (c *accountConfig) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Username
+}
+
+// newAccount 根據配置創建帳號。prefixLogs 為 true 時該帳號的日誌帶有帳號名稱，以便區分多個帳號。
+func newAccount(config accountConfig, prefixLogs bool) (*account, error) {
+	pushTimeTable := config.PushTimes
+	if pushTimeTable == "" {
+		pushTimeTable = os.Getenv("PUSH_TIME_TABLE")
+	}
+	pushTimes, err := parsePushTimeTable(pushTimeTable)
+	if err != nil {
+		return nil, fmt.Errorf("解析推送時間失敗: %w", err)
+	}
+
+	name := config.displayName()
+	if name == "" {
+		name = "default"
+	}
+	acct := &account{
+		name:        name,
+		pushTimes:   pushTimes,
+		prefixLogs:  prefixLogs,
+		username:    config.Username,
+		password:    config.Password,
+		sessionFile: config.SessionFile,
+		scheduler:   &SchedulerStatus{},
+	}
+	acct.logger = acct.componentLogger(logging.MainComponent)
+	acct.guard = &loginGuard{logger: acct.logger, configHint: "CourseTool.env 中的 SDTBU_USERNAME / SDTBU_PASSWORD"}
+	if prefixLogs {
+		acct.guard.configHint = fmt.Sprintf("ACCOUNTS_FILE 中帳號 %s 的 username / password", name)
+	}
+	for _, openID := range config.OpenIDs {
+		acct.recipients = append(acct.recipients, wxpush.Recipient{OpenID: openID, TemplateID: config.TemplateID, NoticeTemplateID: config.NoticeTemplateID})
+	}
+
+	key := config.Username
+	if key == "" {
+		key = name
+	}
+	acct.timetable = newTimetableStore(acct, key)
+	return acct, nil
+}
+
+// componentLogger 返回 component 中屬於該帳號的日誌輸出
+func (a *account) componentLogger(component string) *slog.Logger {
+	logger := logging.Component(component)
+	if a.prefixLogs {
+		logger = logger.With(logging.KeyAccount, a.name)
+	}
+	return logger
+}
+
+// findAccount 按名稱或學號查找帳號
+func findAccount(name string) *account {
+	for _, acct := range accounts {
+		if strings.EqualFold(acct.name, name) || acct.timetable.key == name {
+			return acct
+		}
+	}
+	return nil
+}
+
+// reloadCredentials 重新讀取該帳號的學號與密碼，用於 /relogin
+func (a *account) reloadCredentials() {
+	username, password := os.Getenv("SDTBU_USERNAME"), os.Getenv("SDTBU_PASSWORD")
+	if path := os.Getenv("ACCOUNTS_FILE"); path != "" {
+		configs, err := readAccountsFile(path)
+		if err != nil {
+			a.logger.Warn("重新讀取帳號文件失敗，將使用原有的帳號密碼", "err", err)
+			return
+		}
+		found := false
+		for _, config := range configs {
+			if config.displayName() == a.name {
+				username, password, found = config.Username, config.Password, true
+				break
+			}
+		}
+		if !found {
+			a.logger.Warn("帳號文件中已沒有該帳號，將使用原有的帳號密碼。")
+			return
+		}
+	}
+	a.sessionMu.Lock()
+	a.username, a.password = username, password
+	a.sessionMu.Unlock()
+}
+
+// start 並行啟動該帳號的課程推送、考試提醒與成績輪詢
+func (a *account) start(ctx context.Context) {
+	a.goJob(ctx, "課程推送", a.runScheduler)
+	a.goJob(ctx, "考試提醒", a.runExamReminders)
+	a.goJob(ctx, "成績輪詢", a.runGradePolling)
+}
+
+// goJob 在新的 Goroutine 中運行該帳號的後台任務，任務崩潰時只停止該任務並記錄堆棧，不影響其他帳號
+func (a *account) goJob(ctx context.Context, name string, job func(context.Context)) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				a.logger.Error(name+"任務崩潰，已停止該任務", "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+		job(ctx)
+	}()
+}
+
+// splitList 將以逗號分隔的字串拆分為非空的項目
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"CourseTool/logging"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logger 返回主程式的日誌輸出，屬於某個帳號的日誌使用 account.logger
+func logger() *slog.Logger {
+	return logging.Component(logging.MainComponent)
+}
+
+// fatal 以 error 級別記錄日誌後退出程式
+func fatal(msg string, args ...any) {
+	logger().Error(msg, args...)
+	logging.Close()
+	os.Exit(1)
+}
+
+// portalLogger 將 sdtbu 的日誌轉發到 slog，sdtbu 只提供純文字消息，級別、時間戳與帳號屬性由 slog 處理
+type portalLogger struct {
+	logger *slog.Logger
+}
+
+// Debugf 以 debug 級別記錄請求狀態等細節
+func (p portalLogger) Debugf(format string, args ...interface{}) {
+	p.logger.Debug(fmt.Sprintf(format, args...))
+}
+
+// Infof 以 info 級別記錄正常進度
+func (p portalLogger) Infof(format string, args ...interface{}) {
+	p.logger.Info(fmt.Sprintf(format, args...))
+}
+
+// Warnf 以 warn 級別記錄可以自動恢復的問題
+func (p portalLogger) Warnf(format string, args ...interface{}) {
+	p.logger.Warn(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	ASNIColor "CourseTool/asnicolor"
+	"CourseTool/sdtbu"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 驗證碼識別的預設設定
+const (
+	defaultCaptchaImageFile = "cache/captcha" // 驗證碼圖片的保存路徑 (不含擴展名)
+	defaultCaptchaTimeout   = 5 * time.Minute // 等待用戶輸入驗證碼的最長時間
+)
+
+// replCaptchaSolver 將驗證碼圖片保存到文件，並等待用戶在控制台輸入 /captcha <驗證碼>
+type replCaptchaSolver struct {
+	imagePath   string
+	timeout     time.Duration
+	mu          sync.Mutex  // 同一時間只處理一個驗證碼
+	pending     atomic.Bool // 是否正在等待用戶輸入
+	interactive atomic.Bool // 控制台輸入循環是否已啟動
+	answers     chan string
+}
+
+// globalCaptchaSolver 是控制台驗證碼輸入的全局實例
+var globalCaptchaSolver = newReplCaptchaSolver()
+
+// newReplCaptchaSolver 根據環境變數創建控制台驗證碼輸入
+func newReplCaptchaSolver() *replCaptchaSolver {
+	imagePath := os.Getenv("CAPTCHA_IMAGE_FILE")
+	if imagePath == "" {
+		imagePath = defaultCaptchaImageFile
+	}
+	return &replCaptchaSolver{
+		imagePath: imagePath,
+		timeout:   durationFromEnv("CAPTCHA_TIMEOUT", defaultCaptchaTimeout),
+		answers:   make(chan string, 1),
+	}
+}
+
+// newCaptchaSolver 根據環境變數創建驗證碼識別方式：
+// CAPTCHA_SOLVER=repl (預設) 在控制台提示輸入，command 調用 CAPTCHA_COMMAND，none 不處理驗證碼。
+func newCaptchaSolver() sdtbu.CaptchaSolver {
+	switch mode := strings.ToLower(os.Getenv("CAPTCHA_SOLVER")); mode {
+	case "none":
+		return nil
+	case "command":
+		fields := strings.Fields(os.Getenv("CAPTCHA_COMMAND"))
+		if len(fields) == 0 {
+			logger().Warn("CAPTCHA_SOLVER=command 但未設定 CAPTCHA_COMMAND，將在控制台提示輸入驗證碼。")
+			break
+		}
+		return &sdtbu.CommandCaptchaSolver{Command: fields[0], Args: fields[1:], Timeout: globalCaptchaSolver.timeout}
+	case "", "repl":
+	default:
+		logger().Warn("未知的 CAPTCHA_SOLVER，將在控制台提示輸入驗證碼。", "value", mode)
+	}
+	return globalCaptchaSolver
+}
+
+// Solve 保存驗證碼圖片並等待用戶輸入
+func (s *replCaptchaSolver) Solve(ctx context.Context, challenge *sdtbu.CaptchaChallenge) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(s.imagePath, filepath.Ext(s.imagePath)) + challenge.Extension()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("創建驗證碼目錄失敗: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, challenge.Image, 0o600); err != nil {
+		return "", fmt.Errorf("保存驗證碼圖片失敗: %w", err)
+	}
+	defer os.Remove(path)
+
+	// 控制台輸入循環尚未啟動時 (例如 -export-ics)，直接從標準輸入讀取
+	if !s.interactive.Load() {
+		fmt.Printf(ASNIColor.BrightYellow+"登入需要驗證碼，請打開 %s 查看並輸入驗證碼: "+ASNIColor.Reset, path)
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("讀取驗證碼失敗: %w", err)
+		}
+		return strings.TrimSpace(input), nil
+	}
+
+	select {
+	case <-s.answers: // 丟棄之前殘留的輸入
+	default:
+	}
+	s.pending.Store(true)
+	defer s.pending.Store(false)
+
+	fmt.Printf(ASNIColor.BrightYellow+"\n登入需要驗證碼，請打開 %s 查看，並在 %s 內輸入 /captcha <驗證碼>。\n"+ASNIColor.Reset, path, s.timeout)
+	fmt.Print(ASNIColor.BrightBlue + "> " + ASNIColor.Reset)
+	select {
+	case answer := <-s.answers:
+		return answer, nil
+	case <-time.After(s.timeout):
+		return "", fmt.Errorf("等待輸入驗證碼超時 (%s)", s.timeout)
+	case <-ctx.Done():
+		return "", fmt.Errorf("等待輸入驗證碼時被取消: %w", ctx.Err())
+	}
+}
+
+// offer 在等待驗證碼時接收控制台輸入。輸入 /captcha <驗證碼> 或直接輸入驗證碼均可，
+// 其他命令照常交給控制台處理。返回 true 表示該行已作為驗證碼使用。
+func (s *replCaptchaSolver) offer(line string) bool {
+	if !s.pending.Load() {
+		return false
+	}
+	answer := strings.TrimSpace(line)
+	if fields := strings.Fields(answer); len(fields) == 2 && strings.EqualFold(fields[0], "/captcha") {
+		answer = fields[1]
+	} else if answer == "" || strings.HasPrefix(answer, "/") {
+		return false
+	}
+	select {
+	case s.answers <- answer:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+// Package clock 提供可替換的時間來源，供 sdtbu 與排程器計算下一節課、教學週與推送時間。
+// 正常運行時使用系統時鐘；模擬某個時刻 (--now、/simulate) 或編寫確定性的測試時替換為其他實現。
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 是時間來源
+type Clock interface {
+	Now() time.Time                         // 當前時刻
+	After(d time.Duration) <-chan time.Time // 經過 d 之後發送當前時刻
+}
+
+// System 是使用系統時間的 Clock
+type System struct{}
+
+// Now 返回 time.Now()
+func (System) Now() time.Time { return time.Now() }
+
+// After 返回 time.After(d)
+func (System) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Offset 是從指定時刻開始、與系統時間同速流逝的 Clock，用於模擬在另一個時刻運行程式
+type Offset struct {
+	offset time.Duration
+}
+
+// NewOffset 創建當前時刻為 at 的 Offset
+func NewOffset(at time.Time) *Offset {
+	return &Offset{offset: time.Until(at)}
+}
+
+// Now 返回模擬的當前時刻
+func (o *Offset) Now() time.Time { return time.Now().Add(o.offset) }
+
+// After 在真實時間經過 d 之後發送模擬的當前時刻
+func (o *Offset) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	time.AfterFunc(d, func() { ch <- o.Now() })
+	return ch
+}
+
+// Manual 是只在調用 Set 或 Advance 時才前進的 Clock，用於確定性的測試
+type Manual struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+// manualWaiter 是等待 Manual 前進到某一時刻的 After 調用
+type manualWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewManual 創建當前時刻為 at 的 Manual
+func NewManual(at time.Time) *Manual {
+	return &Manual{now: at}
+}
+
+// Now 返回當前時刻
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// After 在時鐘前進 d 之後發送當前時刻，d <= 0 時立即發送
+func (m *Manual) After(d time.Duration) <-chan time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- m.now
+		return ch
+	}
+	m.waiters = append(m.waiters, manualWaiter{at: m.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters 返回尚未到期的 After 調用數量，測試用來等待被測代碼開始等待時鐘
+func (m *Manual) Waiters() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.waiters)
+}
+
+// Advance 將時鐘向前撥動 d，並喚醒所有已到期的 After
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	at := m.now.Add(d)
+	m.mu.Unlock()
+	m.Set(at)
+}
+
+// Set 將時鐘設定為 at，並按到期順序喚醒所有已到期的 After；不允許倒退
+func (m *Manual) Set(at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if at.Before(m.now) {
+		return
+	}
+	m.now = at
+	sort.SliceStable(m.waiters, func(i, j int) bool { return m.waiters[i].at.Before(m.waiters[j].at) })
+	remaining := m.waiters[:0]
+	for _, w := range m.waiters {
+		if w.at.After(at) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.ch <- at
+	}
+	m.waiters = remaining
+}
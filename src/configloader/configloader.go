@@ -1,7 +1,7 @@
 package configloader
 
 import (
-	"log"
+	"CourseTool/logging"
 	// "os" // Uncomment if you need to construct absolute paths for .env
 	// "path/filepath" // Uncomment if you need to construct absolute paths for .env
 
@@ -15,11 +15,23 @@ func init() {
 	// When you run E:\DEV\Go\CourseTool\temp\CourseTool.exe,
 	// and CourseTool.env is also in E:\DEV\Go\CourseTool\temp\, this will find it.
 	err := godotenv.Load("CourseTool.env")
+
+	// 日誌設定 (LOG_LEVEL 等) 可能寫在 CourseTool.env 中，因此在載入之後才配置日誌輸出
+	config, configErr := logging.ConfigFromEnv()
+	setupErr := logging.Setup(config)
+	logger := logging.Component("configloader")
+	if configErr != nil {
+		logger.Warn("日誌設定無效，已使用預設值", "err", configErr)
+	}
+	if setupErr != nil {
+		logger.Warn("日誌將只輸出到控制台", "err", setupErr)
+	}
+
 	if err != nil {
 		// It's common for .env files to be optional, especially in production
 		// where env vars are set directly. So, a warning is often sufficient.
-		log.Printf("CONFIGLOADER: Note: Error loading CourseTool.env file: %v. Will rely on system-set environment variables if they are present.", err)
+		logger.Info("Note: Error loading CourseTool.env file. Will rely on system-set environment variables if they are present.", "err", err)
 	} else {
-		log.Println("CONFIGLOADER: CourseTool.env loaded successfully.")
+		logger.Info("CourseTool.env loaded successfully.")
 	}
 }
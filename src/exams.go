@@ -0,0 +1,295 @@
+package main
+
+import (
+	ASNIColor "CourseTool/asnicolor"
+	"CourseTool/sdtbu"
+	"CourseTool/wxpush"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultExamReminders 是未設定 EXAM_REMINDERS 時考試前多久發送提醒
+var defaultExamReminders = []time.Duration{24 * time.Hour, time.Hour}
+
+// defaultExamRefreshInterval 是未設定 EXAM_REFRESH_INTERVAL 時重新獲取考試安排的間隔
+const defaultExamRefreshInterval = 6 * time.Hour
+
+// examRefreshRetryDelay 是保存的考試安排已過期且刷新失敗時，再次嘗試從門戶獲取前的等待時間
+const examRefreshRetryDelay = 10 * time.Minute
+
+// examRefreshInterval 是從門戶重新獲取考試安排的間隔，考試安排與課表分開保存，不受課表緩存有效期影響
+var examRefreshInterval = durationFromEnv("EXAM_REFRESH_INTERVAL", defaultExamRefreshInterval)
+
+// parseExamReminders 解析 EXAM_REMINDERS，例如 "24h,1h"；設定為 off 時不發送考試提醒
+func parseExamReminders() ([]time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("EXAM_REMINDERS"))
+	switch strings.ToLower(value) {
+	case "":
+		return defaultExamReminders, nil
+	case "off", "none":
+		return nil, nil
+	}
+	var offsets []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || offset <= 0 {
+			return nil, fmt.Errorf("無效的提醒時間 '%s'", part)
+		}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// extractExamInfo 將考試轉換為課程提醒模板中的欄位，考試與課程共用同一個微信模板
+func extractExamInfo(exam *sdtbu.Exam) (courseName, teacherName, location, timeNumber string) {
+	courseName = "【考試】" + exam.Course
+	teacherName = "考試"
+	if exam.Seat != "" {
+		teacherName = "座位號 " + exam.Seat
+	}
+	location = exam.Location
+	if location == "" {
+		location = "未知考場"
+	}
+	return courseName, teacherName, location, exam.TimeLabel()
+}
+
+// examStore 返回該帳號保存考試安排的位置，與課表緩存使用相同的目錄與帳號鍵
+func (a *account) examStore() (*sdtbu.ExamStore, string) {
+	return &sdtbu.ExamStore{Dir: a.timetable.cache.Dir}, a.timetable.key
+}
+
+// loadExams 返回該帳號的考試安排。保存的考試安排超過 EXAM_REFRESH_INTERVAL 或 force 時從門戶重新獲取，
+// 定期刷新失敗時繼續使用保存的考試安排；仍存在的考試的已發送提醒保留到新的考試安排中。
+func (a *account) loadExams(ctx context.Context, force bool) (*sdtbu.ExamSnapshot, error) {
+	a.examsMu.Lock()
+	defer a.examsMu.Unlock()
+
+	store, key := a.examStore()
+	saved, err := store.Load(key)
+	if err != nil && !errors.Is(err, sdtbu.ErrCacheMiss) {
+		a.logger.Warn(err.Error())
+	}
+	if saved != nil && !force && appClock.Now().Sub(saved.FetchedAt) < examRefreshInterval {
+		return saved, nil
+	}
+
+	exams, err := a.fetchExams(ctx)
+	if err != nil {
+		if saved != nil && !force {
+			a.logger.Warn("獲取考試安排失敗，繼續使用保存的考試安排", "fetched_at", saved.FetchedAt.Format("2006-01-02 15:04"), "err", err)
+			return saved, nil
+		}
+		return nil, err
+	}
+
+	snapshot := &sdtbu.ExamSnapshot{Exams: exams, FetchedAt: appClock.Now()}
+	if saved != nil {
+		snapshot.SentReminders = keepSentReminders(saved.SentReminders, exams)
+	}
+	if err := store.Save(key, snapshot); err != nil {
+		a.logger.Warn(err.Error())
+	}
+	return snapshot, nil
+}
+
+// fetchExams 使用共用的門戶會話獲取課表所在學期的考試安排
+func (a *account) fetchExams(ctx context.Context) ([]sdtbu.Exam, error) {
+	timetable, err := a.timetable.Get(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.guard.check(); err != nil {
+		return nil, err
+	}
+	session, err := a.getSession(ctx)
+	if err != nil {
+		a.guard.observe(err)
+		return nil, err
+	}
+	exams, err := session.FetchExams(ctx, &timetable.Semester)
+	if err != nil {
+		a.guard.observe(err)
+		return nil, fmt.Errorf("獲取考試安排失敗: %w", err)
+	}
+	a.logger.Info("已獲取考試安排", "semester", timetable.Semester.String(), "exams", len(exams))
+	return exams, nil
+}
+
+// keepSentReminders 返回 sent 中仍存在於 exams 的考試的提醒標識，已取消或改期的考試的記錄不再保留
+func keepSentReminders(sent []string, exams []sdtbu.Exam) []string {
+	current := make(map[string]bool, len(exams))
+	for i := range exams {
+		current[exams[i].Key()] = true
+	}
+	var kept []string
+	for _, key := range sent {
+		examKey, _, _ := strings.Cut(key, "|")
+		if current[examKey] {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
+// markRemindersSent 將提醒標記為已發送並保存，程式重啟後不會重複發送
+func (a *account) markRemindersSent(keys []string) {
+	a.examsMu.Lock()
+	defer a.examsMu.Unlock()
+
+	store, key := a.examStore()
+	snapshot, err := store.Load(key)
+	if err != nil {
+		a.logger.Warn("記錄已發送的考試提醒失敗", "err", err)
+		return
+	}
+	for _, reminder := range keys {
+		if !slices.Contains(snapshot.SentReminders, reminder) {
+			snapshot.SentReminders = append(snapshot.SentReminders, reminder)
+		}
+	}
+	if err := store.Save(key, snapshot); err != nil {
+		a.logger.Warn(err.Error())
+	}
+}
+
+// nextExamBefore 返回在下一節課開始之前進行的考試，使下一個提醒總是最早發生的日程。
+// class 為 nil (例如考試週沒有課程) 時返回 now 之後的下一場考試；沒有符合的考試時返回 nil。
+func (a *account) nextExamBefore(ctx context.Context, class *sdtbu.ClassOccurrence, now time.Time) *sdtbu.Exam {
+	snapshot, err := a.loadExams(ctx, false)
+	if err != nil {
+		return nil
+	}
+	exam := snapshot.Next(now)
+	if exam == nil || class == nil || !class.Start.Before(exam.Start) {
+		return exam
+	}
+	return nil
+}
+
+// printUpcomingExams 打印尚未結束的考試安排
+func (a *account) printUpcomingExams(ctx context.Context) {
+	snapshot, err := a.loadExams(ctx, false)
+	if err != nil {
+		fmt.Printf(ASNIColor.Red+"錯誤: 無法獲取考試安排: %v\n"+ASNIColor.Reset, err)
+		return
+	}
+	exams := snapshot.Upcoming(appClock.Now())
+	if len(exams) == 0 {
+		fmt.Println(ASNIColor.Yellow + "目前沒有尚未進行的考試安排。" + ASNIColor.Reset)
+		return
+	}
+	fmt.Printf(ASNIColor.BrightYellow+"考試安排 (獲取於 %s)：\n"+ASNIColor.Reset, snapshot.FetchedAt.Format("2006-01-02 15:04"))
+	for i := range exams {
+		courseName, seat, location, timeLabel := extractExamInfo(&exams[i])
+		fmt.Printf("%s  %s  %s  %s\n", timeLabel, courseName, location, seat)
+	}
+}
+
+// runExamReminders 在每場考試開始前按 EXAM_REMINDERS 發送提醒 (預設提前 24 小時與 1 小時)。
+// 已錯過的提醒時間只在考試開始前補發一次，已發送的提醒與考試安排一起保存，重啟後不會重複發送。
+func (a *account) runExamReminders(ctx context.Context) {
+	offsets, err := parseExamReminders()
+	if err != nil {
+		a.logger.Warn("EXAM_REMINDERS 無效，將使用預設值。", "err", err)
+		offsets = defaultExamReminders
+	}
+	if len(offsets) == 0 {
+		a.logger.Info("考試提醒已關閉。")
+		return
+	}
+
+	for {
+		loadCtx, cancelLoad := context.WithTimeout(ctx, operationTimeout)
+		snapshot, err := a.loadExams(loadCtx, false)
+		cancelLoad()
+
+		wait := examRefreshInterval
+		var due *sdtbu.Exam
+		if err != nil {
+			a.logger.Warn("獲取考試安排失敗，稍後重試", "retry_in", examRefreshInterval, "err", err)
+		} else {
+			now := appClock.Now()
+			// 保存的考試安排到期後重新獲取；已過期說明剛才刷新失敗，等待一段時間再重試，避免門戶不可用時連續請求
+			refreshIn := snapshot.FetchedAt.Add(examRefreshInterval).Sub(now)
+			if refreshIn <= 0 {
+				refreshIn = examRefreshRetryDelay
+			}
+			if refreshIn < wait {
+				wait = refreshIn
+			}
+			exams := snapshot.Upcoming(now)
+			for i := range exams {
+				for _, offset := range offsets {
+					if slices.Contains(snapshot.SentReminders, reminderKey(&exams[i], offset)) || !now.Before(exams[i].Start) {
+						continue
+					}
+					if until := exams[i].Start.Add(-offset).Sub(now); until < wait {
+						wait, due = until, &exams[i]
+					}
+				}
+			}
+		}
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-appClock.After(wait):
+			}
+		}
+		if due == nil {
+			continue // 到了重新檢查的時間，重新讀取考試安排
+		}
+
+		// 將所有已到時間的提醒標記為已發送，錯過多個提醒時只補發一次
+		now := appClock.Now()
+		var reached []string
+		for _, offset := range offsets {
+			if !now.Before(due.Start.Add(-offset)) {
+				reached = append(reached, reminderKey(due, offset))
+			}
+		}
+		a.markRemindersSent(reached)
+		if !now.Before(due.Start) {
+			continue
+		}
+		a.logger.Info("觸發考試提醒", "course", due.Course, "starts_in", due.Start.Sub(now).Round(time.Minute))
+		pushCtx, cancelPush := context.WithTimeout(ctx, operationTimeout)
+		courseName, teacherName, location, timeNumber := extractExamInfo(due)
+		// 考試提醒不附帶每日備註，備註欄位用於提示距離開考的時間
+		a.sendCourseReminder(pushCtx, wxpush.CourseReminderData{
+			CourseName:     courseName,
+			TeacherName:    teacherName,
+			CourseLocation: location,
+			TimeNumber:     timeNumber,
+			Note:           fmt.Sprintf("距離開考還有 %s，請攜帶學生證提前到達考場", formatUntil(due.Start.Sub(now))),
+		})
+		cancelPush()
+	}
+}
+
+// reminderKey 返回某場考試某個提前量的提醒標識
+func reminderKey(exam *sdtbu.Exam, offset time.Duration) string {
+	return exam.Key() + "|" + offset.String()
+}
+
+// formatUntil 返回距離開考時間的顯示文字，例如 "1 小時 30 分鐘"
+func formatUntil(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d 分鐘", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%d 小時", hours)
+	}
+	return fmt.Sprintf("%d 小時 %d 分鐘", hours, minutes)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"CourseTool/clock"
+	"CourseTool/sdtbu"
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBuffer 是可併發寫入的日誌緩衝區
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// runRemindersOnce 以 dir 中保存的考試安排啟動一次考試提醒，等待其開始休眠後停止，返回期間的日誌
+func runRemindersOnce(t *testing.T, manual *clock.Manual, dir string) string {
+	t.Helper()
+	logs := &logBuffer{}
+	acct := &account{name: "test", logger: slog.New(slog.NewTextHandler(logs, nil))}
+	acct.guard = &loginGuard{logger: acct.logger, permanent: true, reason: "測試中不連接門戶"} // 刷新考試安排總是失敗
+	acct.timetable = &timetableStore{account: acct, cache: &sdtbu.TimetableCache{Dir: dir}, key: "test"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		acct.runExamReminders(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for manual.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("考試提醒沒有開始等待")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	return logs.String()
+}
+
+func TestExamRemindersSurviveRestart(t *testing.T) {
+	t.Setenv("EXAM_REMINDERS", "24h,1h")
+	now := time.Date(2025, 6, 19, 8, 30, 0, 0, time.Local)
+	manual := clock.NewManual(now)
+	appClock = manual
+	t.Cleanup(func() { appClock = clock.System{} })
+
+	// 考試半小時後開始，兩個提醒時間都已錯過，只補發一次
+	exam := sdtbu.Exam{Course: "大學英語", Start: now.Add(30 * time.Minute), End: now.Add(150 * time.Minute), Location: "2號教學樓203"}
+	dir := t.TempDir()
+	store := &sdtbu.ExamStore{Dir: dir}
+	if err := store.Save("test", &sdtbu.ExamSnapshot{Exams: []sdtbu.Exam{exam}, FetchedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	if logs := runRemindersOnce(t, manual, dir); strings.Count(logs, "觸發考試提醒") != 1 {
+		t.Fatalf("第一次運行應補發一次提醒，日誌:\n%s", logs)
+	}
+	snapshot, err := store.Load("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.SentReminders) != 2 {
+		t.Errorf("SentReminders = %v, want 兩個提前量", snapshot.SentReminders)
+	}
+
+	// 重啟後讀取保存的記錄，不再重複發送
+	if logs := runRemindersOnce(t, manual, dir); strings.Contains(logs, "觸發考試提醒") {
+		t.Errorf("重啟後重複發送了提醒，日誌:\n%s", logs)
+	}
+}
+
+func TestExamRemindersBackOffWhenRefreshFails(t *testing.T) {
+	t.Setenv("EXAM_REMINDERS", "24h,1h")
+	now := time.Date(2025, 6, 19, 8, 30, 0, 0, time.Local)
+	manual := clock.NewManual(now)
+	appClock = manual
+	t.Cleanup(func() { appClock = clock.System{} })
+
+	// 保存的考試安排已過期，刷新失敗時繼續使用，且考試還早，沒有要發送的提醒
+	exam := sdtbu.Exam{Course: "高等數學", Start: now.AddDate(0, 0, 7), End: now.AddDate(0, 0, 7).Add(2 * time.Hour)}
+	dir := t.TempDir()
+	store := &sdtbu.ExamStore{Dir: dir}
+	if err := store.Save("test", &sdtbu.ExamSnapshot{Exams: []sdtbu.Exam{exam}, FetchedAt: now.Add(-2 * examRefreshInterval)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 刷新失敗後應開始等待，而不是立即再次請求門戶
+	logs := runRemindersOnce(t, manual, dir)
+	if n := strings.Count(logs, "獲取考試安排失敗"); n != 1 {
+		t.Errorf("刷新失敗後嘗試了 %d 次，want 1，日誌:\n%s", n, logs)
+	}
+}
+
+func TestKeepSentReminders(t *testing.T) {
+	start := time.Date(2025, 6, 19, 9, 0, 0, 0, time.Local)
+	english := sdtbu.Exam{Course: "大學英語", Start: start}
+	moved := sdtbu.Exam{Course: "高等數學", Start: start.AddDate(0, 0, 7)}
+	sent := []string{
+		reminderKey(&english, 24*time.Hour),
+		reminderKey(&sdtbu.Exam{Course: "高等數學", Start: start.AddDate(0, 0, 6)}, 24*time.Hour), // 改期前的時間
+	}
+
+	kept := keepSentReminders(sent, []sdtbu.Exam{english, moved})
+	if len(kept) != 1 || kept[0] != sent[0] {
+		t.Errorf("keepSentReminders = %v, want [%s]", kept, sent[0])
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"CourseTool/sdtbu/fakeportal"
+	"fmt"
+	"os"
+)
+
+// 使用 -fake-portal 啟動時的模擬門戶，為 nil 時連接智慧山商
+var (
+	fakePortal    *fakeportal.Server
+	fakePortalDir string // 模擬門戶的會話與課表緩存目錄，與真實帳號的數據隔離
+)
+
+// startFakePortal 啟動進程內的模擬門戶，之後的登入、課表獲取與推送都使用模擬數據。
+// mode 為 direct 或 webvpn；返回的函數用於關閉模擬門戶並清理臨時目錄。
+func startFakePortal(mode string) (func(), error) {
+	server := fakeportal.New()
+	switch mode {
+	case "direct":
+	case "webvpn":
+		server.WebVPN = true
+	default:
+		return nil, fmt.Errorf("未知的模擬門戶模式 '%s' (可選 direct、webvpn)", mode)
+	}
+
+	dir, err := os.MkdirTemp("", "coursetool-fake-portal-")
+	if err != nil {
+		return nil, fmt.Errorf("創建模擬門戶的臨時目錄失敗: %w", err)
+	}
+	server.Start()
+	fakePortal, fakePortalDir = server, dir // 之後創建的帳號將課表緩存與會話保存在臨時目錄中，避免覆蓋真實數據
+
+	logger().Info("已啟動模擬門戶，所有數據均為示例數據。", "mode", mode, "url", server.URL)
+	return func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
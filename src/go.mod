@@ -6,3 +6,5 @@ require (
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/net v0.40.0
 )
+
+require golang.org/x/text v0.25.0 // indirect
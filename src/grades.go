@@ -0,0 +1,179 @@
+package main
+
+import (
+	ASNIColor "CourseTool/asnicolor"
+	"CourseTool/sdtbu"
+	"CourseTool/wxpush"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultGradePollInterval 是未設定 GRADE_POLL_INTERVAL 時檢查新成績的間隔
+const defaultGradePollInterval = 2 * time.Hour
+
+// parseGradePollInterval 解析 GRADE_POLL_INTERVAL (Go duration 格式)；設定為 off 時返回 0，表示不輪詢
+func parseGradePollInterval() time.Duration {
+	value := strings.TrimSpace(os.Getenv("GRADE_POLL_INTERVAL"))
+	switch strings.ToLower(value) {
+	case "":
+		return defaultGradePollInterval
+	case "off", "none":
+		return 0
+	}
+	return durationFromEnv("GRADE_POLL_INTERVAL", defaultGradePollInterval)
+}
+
+// gradeStore 返回該帳號保存成績快照的位置，與課表緩存使用相同的目錄與帳號鍵
+func (a *account) gradeStore() (*sdtbu.GradeStore, string) {
+	return &sdtbu.GradeStore{Dir: a.timetable.cache.Dir}, a.timetable.key
+}
+
+// checkGrades 從門戶獲取最新成績，與上一次保存的快照比較後推送新出現或變化的成績，並更新快照。
+// 第一次運行時沒有快照，只記錄當前成績作為基準，不推送已公佈的舊成績。
+func (a *account) checkGrades(ctx context.Context) ([]sdtbu.Grade, error) {
+	a.gradesMu.Lock()
+	defer a.gradesMu.Unlock()
+
+	if err := a.guard.check(); err != nil {
+		return nil, err
+	}
+	session, err := a.getSession(ctx)
+	if err != nil {
+		a.guard.observe(err)
+		return nil, err
+	}
+	grades, err := session.FetchGrades(ctx)
+	if err != nil {
+		a.guard.observe(err)
+		return nil, fmt.Errorf("獲取成績失敗: %w", err)
+	}
+
+	store, key := a.gradeStore()
+	previous, err := store.Load(key)
+	if err != nil && !errors.Is(err, sdtbu.ErrCacheMiss) {
+		a.logger.Warn(err.Error())
+	}
+
+	if previous == nil {
+		a.logger.Info("已記錄當前成績作為基準，之後公佈的新成績將會推送。", "courses", len(grades))
+	} else {
+		a.notifyGradeChanges(ctx, previous.Grades, grades)
+	}
+
+	if err := store.Save(key, &sdtbu.GradeSnapshot{Grades: grades, FetchedAt: appClock.Now()}); err != nil {
+		a.logger.Warn(err.Error())
+	}
+	return grades, nil
+}
+
+// notifyGradeChanges 逐條推送新出現或變化的成績，附帶該成績對 GPA 的影響
+func (a *account) notifyGradeChanges(ctx context.Context, previous, current []sdtbu.Grade) {
+	changes := sdtbu.DiffGrades(previous, current)
+	if len(changes) == 0 {
+		return
+	}
+	a.logger.Info("發現新成績或成績變化", "changes", len(changes))
+
+	// 依次應用每條變化，使每條推送中的 GPA 變化只反映該門課程
+	applied := append([]sdtbu.Grade(nil), previous...)
+	for i := range changes {
+		before := applied
+		applied = applyGradeChange(applied, &changes[i])
+		a.sendNotice(ctx, extractGradeInfo(&changes[i], before, applied))
+	}
+}
+
+// applyGradeChange 返回在 grades 中加入或替換該條成績後的新列表，不修改原列表
+func applyGradeChange(grades []sdtbu.Grade, change *sdtbu.GradeChange) []sdtbu.Grade {
+	updated := make([]sdtbu.Grade, 0, len(grades)+1)
+	replaced := false
+	for _, grade := range grades {
+		if grade.Key() == change.Grade.Key() {
+			updated = append(updated, change.Grade)
+			replaced = true
+			continue
+		}
+		updated = append(updated, grade)
+	}
+	if !replaced {
+		updated = append(updated, change.Grade)
+	}
+	return updated
+}
+
+// extractGradeInfo 將成績變化轉換為通知，內容為成績與 GPA 變化，補充說明為學期
+func extractGradeInfo(change *sdtbu.GradeChange, before, after []sdtbu.Grade) wxpush.NoticeData {
+	scoreLabel := "成績 " + change.Grade.String()
+	if change.Previous != nil {
+		scoreLabel = fmt.Sprintf("成績更新 %s → %s", change.Previous.String(), change.Grade.String())
+	}
+	return wxpush.NoticeData{
+		Title:   "【成績】" + change.Grade.Course,
+		Content: scoreLabel + "，" + gpaImpact(before, after),
+		Remark:  change.Grade.TermLabel(),
+	}
+}
+
+// gpaImpact 返回 GPA 變化的顯示文字，例如 "GPA 3.42 → 3.51 (+0.09)"
+func gpaImpact(before, after []sdtbu.Grade) string {
+	newGPA, ok := sdtbu.GPA(after)
+	if !ok {
+		return "不計入 GPA"
+	}
+	oldGPA, ok := sdtbu.GPA(before)
+	if !ok {
+		return fmt.Sprintf("GPA %.2f", newGPA)
+	}
+	return fmt.Sprintf("GPA %.2f → %.2f (%+.2f)", oldGPA, newGPA, newGPA-oldGPA)
+}
+
+// printGrades 檢查新成績並打印全部成績與 GPA
+func (a *account) printGrades(ctx context.Context) {
+	grades, err := a.checkGrades(ctx)
+	if err != nil {
+		fmt.Printf(ASNIColor.Red+"錯誤: 無法獲取成績: %v\n"+ASNIColor.Reset, err)
+		return
+	}
+	if len(grades) == 0 {
+		fmt.Println(ASNIColor.Yellow + "目前沒有已公佈的成績。" + ASNIColor.Reset)
+		return
+	}
+	term := ""
+	for i := range grades {
+		if label := grades[i].TermLabel(); label != term {
+			term = label
+			fmt.Println(ASNIColor.BrightYellow + term + "：" + ASNIColor.Reset)
+		}
+		fmt.Printf("  %s  %s\n", grades[i].Course, grades[i].String())
+	}
+	if gpa, ok := sdtbu.GPA(grades); ok {
+		fmt.Printf(ASNIColor.BrightGreen+"加權平均績點 (GPA)：%.2f\n"+ASNIColor.Reset, gpa)
+	}
+}
+
+// runGradePolling 每隔 GRADE_POLL_INTERVAL 檢查一次成績，發現新成績時推送通知
+func (a *account) runGradePolling(ctx context.Context) {
+	interval := parseGradePollInterval()
+	if interval <= 0 {
+		a.logger.Info("成績輪詢已關閉。")
+		return
+	}
+
+	for {
+		checkCtx, cancelCheck := context.WithTimeout(ctx, operationTimeout)
+		if _, err := a.checkGrades(checkCtx); err != nil {
+			a.logger.Warn("檢查成績失敗，稍後重試", "retry_in", interval, "err", err)
+		}
+		cancelCheck()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-appClock.After(interval):
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package httpclient 創建所有對外 HTTP 請求共用的 http.Client。
+// 門戶、微信推送、更新檢查與備註獲取都通過 New 獲取客戶端，從而統一使用同一套代理、CA 證書、超時、
+// User-Agent 以及 httpretry 的重試與熔斷設定。
+package httpclient
+
+import (
+	"CourseTool/httpretry"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// 預設超時時間
+const (
+	DefaultTimeout        = 30 * time.Second // 單個請求 (含重試與讀取響應) 的超時時間
+	DefaultConnectTimeout = 10 * time.Second // 建立 TCP 連接與 TLS 握手各自的超時時間
+)
+
+// Config 是對外 HTTP 請求的設定
+type Config struct {
+	ProxyURL       string        // 代理地址，支持 http、https 與 socks5；為空時使用 HTTP_PROXY、HTTPS_PROXY 與 NO_PROXY
+	CAFile         string        // 額外信任的 PEM 格式 CA 證書文件，例如校園網的中間人代理證書
+	Timeout        time.Duration // 單個請求 (含重試與讀取響應) 的超時時間，<= 0 時不限制
+	ConnectTimeout time.Duration // 建立連接與 TLS 握手的超時時間，<= 0 時使用 DefaultConnectTimeout
+	UserAgent      string        // 請求未設定 User-Agent 時使用的值，為空時使用 Go 的預設值
+}
+
+var (
+	mu        sync.RWMutex
+	config    = Config{Timeout: DefaultTimeout}
+	transport http.RoundTripper // 按 config 創建的共用 Transport，由 Configure 或首次調用 New 時創建
+)
+
+// Configure 驗證設定並使之後通過 New 創建的客戶端使用該設定。
+// 已經創建的客戶端不受影響，因此應在發出任何請求之前調用。
+func Configure(c Config) error {
+	rt, err := newTransport(c)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	config, transport = c, rt
+	mu.Unlock()
+	return nil
+}
+
+// New 返回使用當前設定的 http.Client。所有客戶端共用同一個帶重試與熔斷的 Transport，
+// 調用方可以在返回的客戶端上設定 Jar 或修改 Timeout (例如下載大文件時)。
+func New() *http.Client {
+	mu.Lock()
+	defer mu.Unlock()
+	if transport == nil {
+		rt, err := newTransport(config)
+		if err != nil {
+			// 預設設定不讀取任何文件，不會失敗
+			panic(err)
+		}
+		transport = rt
+	}
+	return &http.Client{Transport: transport, Timeout: config.Timeout}
+}
+
+// UserAgent 返回設定的 User-Agent，未設定時返回空字串
+func UserAgent() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return config.UserAgent
+}
+
+// newTransport 按設定創建帶重試與熔斷的 Transport
+func newTransport(c Config) (http.RoundTripper, error) {
+	proxy, err := proxyFunc(c.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout := c.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = proxy
+	base.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = connectTimeout
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	var rt http.RoundTripper = base
+	if c.UserAgent != "" {
+		rt = &userAgentTransport{base: base, userAgent: c.UserAgent}
+	}
+	// 偶發的網絡錯誤與 5xx 自動重試，目標主機明顯宕機時熔斷
+	return httpretry.NewTransport(rt), nil
+}
+
+// proxyFunc 返回 Transport 使用的代理選擇函數。發往 localhost 的請求 (例如模擬門戶) 不經過代理。
+func proxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxyURL = strings.TrimSpace(proxyURL); proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("無效的代理地址 '%s'", proxyURL)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("不支持的代理協議 '%s'，可選 http、https、socks5", u.Scheme)
+		}
+		proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy = proxyURL, proxyURL
+	}
+	proxy := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// loadCertPool 返回系統證書加上 path 中證書的證書池
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取 CA 證書文件 %s 失敗: %w", path, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA 證書文件 %s 中沒有有效的 PEM 證書", path)
+	}
+	return pool, nil
+}
+
+// userAgentTransport 為沒有設定 User-Agent 的請求添加 User-Agent
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,93 @@
+package httpretry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Breaker 是按主機區分的熔斷器：連續失敗達到閾值後在 OpenDuration 內直接拒絕請求，
+// 之後放行一個探測請求，成功則恢復，失敗則再次熔斷。
+type Breaker struct {
+	FailureThreshold int           // 觸發熔斷的連續失敗次數
+	OpenDuration     time.Duration // 熔斷持續時間
+
+	mu    sync.Mutex
+	hosts map[string]*breakerState
+}
+
+// breakerState 記錄單個主機的熔斷狀態
+type breakerState struct {
+	failures  int       // 連續失敗次數
+	openUntil time.Time // 熔斷解除時間，零值表示未熔斷
+	probing   bool      // 半開狀態下是否已有探測請求在進行
+}
+
+// NewBreaker 創建使用預設閾值 (連續 5 次失敗，熔斷 1 分鐘) 的熔斷器
+func NewBreaker() *Breaker {
+	return &Breaker{FailureThreshold: 5, OpenDuration: time.Minute}
+}
+
+// Allow 判斷是否允許向 host 發送請求，熔斷中返回包裝了 ErrCircuitOpen 的錯誤
+func (b *Breaker) Allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.hosts[host]
+	if state == nil || state.openUntil.IsZero() {
+		return nil
+	}
+	if remaining := time.Until(state.openUntil); remaining > 0 {
+		return fmt.Errorf("httpretry: %s 連續請求失敗，將在 %s 後重試: %w", host, remaining.Round(time.Second), ErrCircuitOpen)
+	}
+	if state.probing {
+		return fmt.Errorf("httpretry: %s 正在探測是否恢復: %w", host, ErrCircuitOpen)
+	}
+	state.probing = true // 半開狀態，只放行一個探測請求
+	return nil
+}
+
+// Record 記錄一次請求的結果
+func (b *Breaker) Record(host string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.hosts == nil {
+		b.hosts = make(map[string]*breakerState)
+	}
+	state := b.hosts[host]
+	if state == nil {
+		state = &breakerState{}
+		b.hosts[host] = state
+	}
+	state.probing = false
+
+	if success {
+		if !state.openUntil.IsZero() {
+			logger().Info("已恢復，解除熔斷", "host", host)
+		}
+		state.failures = 0
+		state.openUntil = time.Time{}
+		return
+	}
+
+	state.failures++
+	threshold := b.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	// 探測請求失敗或連續失敗達到閾值時 (重新) 熔斷
+	if !state.openUntil.IsZero() || state.failures >= threshold {
+		state.openUntil = time.Now().Add(b.OpenDuration)
+		logger().Warn("連續請求失敗，熔斷", "host", host, "failures", state.failures, "duration", b.OpenDuration)
+	}
+}
+
+// release 在請求被取消、結果無法判斷時釋放半開狀態的探測名額
+func (b *Breaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state := b.hosts[host]; state != nil {
+		state.probing = false
+	}
+}
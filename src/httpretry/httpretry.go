@@ -0,0 +1,218 @@
+// Package httpretry 提供帶重試與熔斷的 http.RoundTripper，
+// 供門戶、微信推送與更新檢查共用，避免一次偶發的 5xx 就放棄請求。
+package httpretry
+
+import (
+	"CourseTool/logging"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// logger 返回 httpretry 的日誌輸出
+func logger() *slog.Logger {
+	return logging.Component("httpretry")
+}
+
+// ErrCircuitOpen 表示目標主機的熔斷器已打開，請求未被發送
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Policy 描述重試策略
+type Policy struct {
+	MaxAttempts   int           // 最多嘗試次數 (含首次請求)，小於 1 時視為 1
+	BaseDelay     time.Duration // 首次重試前的基礎等待時間，之後每次翻倍
+	MaxDelay      time.Duration // 單次等待時間的上限
+	MaxRetryAfter time.Duration // 服務器要求的 Retry-After 超過此值時不再重試
+}
+
+// DefaultPolicy 是預設的重試策略
+var DefaultPolicy = Policy{
+	MaxAttempts:   3,
+	BaseDelay:     500 * time.Millisecond,
+	MaxDelay:      10 * time.Second,
+	MaxRetryAfter: 30 * time.Second,
+}
+
+// backoff 返回第 attempt 次重試前的等待時間：指數增長並加入隨機抖動 (full jitter)
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// idempotentKey 是標記請求可以安全重試的 context 鍵
+type idempotentKey struct{}
+
+// Idempotent 標記 ctx 上發出的請求可以安全重試。
+// GET、HEAD 等安全方法預設即可重試；只讀的 POST 接口需要調用方顯式標記。
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent 判斷請求重複發送是否安全
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != "" {
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// Transport 是帶重試與熔斷的 http.RoundTripper
+type Transport struct {
+	Base    http.RoundTripper // 實際發送請求的 RoundTripper，為 nil 時使用 http.DefaultTransport
+	Policy  Policy
+	Breaker *Breaker // 為 nil 時不熔斷
+}
+
+// NewTransport 使用預設策略包裝 base，並為每個主機啟用熔斷
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base, Policy: DefaultPolicy, Breaker: NewBreaker()}
+}
+
+// RoundTrip 發送請求，在可重試的失敗後按策略等待並重新發送
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxAttempts := t.Policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	host := req.URL.Host
+
+	for attempt := 1; ; attempt++ {
+		if t.Breaker != nil {
+			if err := t.Breaker.Allow(host); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq := req
+		if attempt > 1 {
+			var err error
+			if attemptReq, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := base.RoundTrip(attemptReq)
+		if t.Breaker != nil {
+			if err != nil && req.Context().Err() != nil {
+				t.Breaker.release(host) // 調用方取消的請求不代表主機故障
+			} else {
+				t.Breaker.Record(host, err == nil && resp.StatusCode < 500)
+			}
+		}
+
+		delay, retry, reason := t.shouldRetry(req, resp, err, attempt)
+		if !retry || attempt >= maxAttempts {
+			return resp, err
+		}
+		if resp != nil {
+			// 丟棄響應以便複用連接
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		logger().Warn("請求失敗，稍後重試", "method", req.Method, "host", host, "reason", reason, "delay", delay.Round(time.Millisecond), "attempt", attempt+1)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry 判斷失敗是否可以重試，並返回重試前的等待時間與原因
+func (t *Transport) shouldRetry(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool, string) {
+	if req.Context().Err() != nil {
+		return 0, false, ""
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false, "" // 無法重放請求主體
+	}
+
+	if err != nil {
+		// 連接未建立時請求肯定沒有被處理，任何方法都可以安全重試
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return t.Policy.backoff(attempt), true, err.Error()
+		}
+		if isIdempotent(req) && !errors.Is(err, ErrCircuitOpen) {
+			return t.Policy.backoff(attempt), true, err.Error()
+		}
+		return 0, false, ""
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// 429 與 503 表示請求未被處理，可以重試任何方法
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusGatewayTimeout:
+		if !isIdempotent(req) {
+			return 0, false, ""
+		}
+	default:
+		return 0, false, ""
+	}
+
+	delay := t.Policy.backoff(attempt)
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		if t.Policy.MaxRetryAfter > 0 && retryAfter > t.Policy.MaxRetryAfter {
+			return 0, false, "" // 服務器要求等待太久，交給調用方處理
+		}
+		delay = retryAfter
+	}
+	return delay, true, resp.Status
+}
+
+// rewindRequest 為重試複製請求並重新獲取請求主體
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("httpretry: 重放請求主體失敗: %w", err)
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// parseRetryAfter 解析 Retry-After 標頭 (秒數或 HTTP 日期)
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	ASNIColor "CourseTool/asnicolor"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ConsoleHandler 以帶顏色的人類可讀格式輸出日誌，例如
+//
+//	2025/03/03 08:00:00 WXPUSH: [alice] 警告: 獲取 Access Token 失敗 err=...
+//
+// 顏色按級別區分，component (主程式除外) 與 account 屬性顯示為前綴，其他屬性以 key=value 附加在消息之後。
+type ConsoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+
+	component string // 由 WithAttrs 設定的 component
+	account   string // 由 WithAttrs 設定的 account
+	attrs     string // 由 WithAttrs 設定的其他屬性，已格式化為 " key=value"
+	group     string // 由 WithGroup 設定的屬性鍵前綴
+}
+
+// NewConsoleHandler 創建寫入 w 的 ConsoleHandler，options 為 nil 時輸出 info 及以上級別
+func NewConsoleHandler(w io.Writer, options *slog.HandlerOptions) *ConsoleHandler {
+	h := &ConsoleHandler{mu: &sync.Mutex{}, w: w, level: slog.LevelInfo}
+	if options != nil && options.Level != nil {
+		h.level = options.Level
+	}
+	return h
+}
+
+// Enabled 報告是否輸出該級別的日誌
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle 輸出一條日誌
+func (h *ConsoleHandler) Handle(_ context.Context, record slog.Record) error {
+	component, account, attrs := h.component, h.account, h.attrs
+	var b strings.Builder
+	b.WriteString(attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		switch {
+		case h.group == "" && attr.Key == KeyComponent:
+			component = attr.Value.String()
+		case h.group == "" && attr.Key == KeyAccount:
+			account = attr.Value.String()
+		default:
+			appendAttr(&b, h.group, attr)
+		}
+		return true
+	})
+
+	var line strings.Builder
+	if !record.Time.IsZero() {
+		line.WriteString(record.Time.Format("2006/01/02 15:04:05 "))
+	}
+	color := levelColor(record.Level)
+	line.WriteString(color)
+	if component != "" && component != MainComponent {
+		line.WriteString(strings.ToUpper(component) + ": ")
+	}
+	if account != "" {
+		line.WriteString("[" + account + "] ")
+	}
+	line.WriteString(levelLabel(record.Level))
+	line.WriteString(record.Message)
+	line.WriteString(b.String())
+	if color != "" {
+		line.WriteString(ASNIColor.Reset)
+	}
+	line.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+// WithAttrs 返回附加了 attrs 的處理器
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, attr := range attrs {
+		switch {
+		case h.group == "" && attr.Key == KeyComponent:
+			clone.component = attr.Value.String()
+		case h.group == "" && attr.Key == KeyAccount:
+			clone.account = attr.Value.String()
+		default:
+			appendAttr(&b, h.group, attr)
+		}
+	}
+	clone.attrs = b.String()
+	return &clone
+}
+
+// WithGroup 返回之後的屬性都位於 name 分組下的處理器
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr 將屬性以 " key=value" 格式寫入 b，分組屬性展開為 group.key
+func appendAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			appendAttr(b, prefix, member)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " =\"\n") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, attr.Key, value)
+}
+
+// levelColor 返回級別對應的顏色，info 使用終端預設顏色
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ASNIColor.Red
+	case level >= slog.LevelWarn:
+		return ASNIColor.Yellow
+	case level >= slog.LevelInfo:
+		return ""
+	default:
+		return ASNIColor.Cyan
+	}
+}
+
+// levelLabel 返回警告與錯誤消息的前綴
+func levelLabel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "錯誤: "
+	case level >= slog.LevelWarn:
+		return "警告: "
+	default:
+		return ""
+	}
+}
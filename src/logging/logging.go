@@ -0,0 +1,226 @@
+// Package logging 基於 log/slog 配置 CourseTool 的日誌輸出。
+// 控制台預設使用帶顏色的格式，也可以改為 text 或 json，以便在 Docker 中收集機器可讀的日誌；
+// 另外可以同時寫入按大小輪轉的日誌文件。每個包使用 Component 獲取帶 component 屬性的 Logger。
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 日誌屬性的鍵
+const (
+	KeyComponent = "component" // 輸出日誌的包，例如 wxpush、sdtbu
+	KeyAccount   = "account"   // 多帳號時日誌所屬的帳號
+)
+
+// MainComponent 是主程式的 component，控制台格式不顯示該前綴
+const MainComponent = "main"
+
+// 日誌格式
+const (
+	FormatConsole = "console" // 帶顏色的人類可讀格式，只用於控制台
+	FormatText    = "text"    // slog 的 key=value 格式
+	FormatJSON    = "json"    // 每行一個 JSON 對象
+)
+
+// 日誌文件輪轉的預設設定
+const (
+	DefaultMaxSizeMB  = 10
+	DefaultMaxBackups = 5
+)
+
+// Config 是日誌輸出設定
+type Config struct {
+	Level      slog.Level // 最低輸出級別
+	Format     string     // 控制台格式：console、text 或 json
+	File       string     // 日誌文件路徑，為空時不寫入文件
+	FileFormat string     // 日誌文件格式：text 或 json
+	MaxSizeMB  int        // 日誌文件超過該大小 (MB) 時輪轉
+	MaxBackups int        // 保留的舊日誌文件數量
+}
+
+// DefaultConfig 返回只輸出 info 及以上級別彩色控制台日誌的設定
+func DefaultConfig() Config {
+	return Config{
+		Level:      slog.LevelInfo,
+		Format:     FormatConsole,
+		FileFormat: FormatJSON,
+		MaxSizeMB:  DefaultMaxSizeMB,
+		MaxBackups: DefaultMaxBackups,
+	}
+}
+
+// ConfigFromEnv 從 LOG_LEVEL、LOG_FORMAT、LOG_FILE、LOG_FILE_FORMAT、LOG_FILE_MAX_SIZE 與 LOG_FILE_MAX_BACKUPS 讀取設定。
+// 無效的值使用預設值代替，並在返回的錯誤中說明。
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	var errs []error
+
+	if value := strings.TrimSpace(os.Getenv("LOG_LEVEL")); value != "" {
+		level, err := ParseLevel(value)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			config.Level = level
+		}
+	}
+	if value := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT"))); value != "" {
+		if value != FormatConsole && value != FormatText && value != FormatJSON {
+			errs = append(errs, fmt.Errorf("未知的 LOG_FORMAT '%s'，可選值為 console、text、json", value))
+		} else {
+			config.Format = value
+		}
+	}
+
+	config.File = strings.TrimSpace(os.Getenv("LOG_FILE"))
+	if value := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FILE_FORMAT"))); value != "" {
+		if value != FormatText && value != FormatJSON {
+			errs = append(errs, fmt.Errorf("未知的 LOG_FILE_FORMAT '%s'，可選值為 text、json", value))
+		} else {
+			config.FileFormat = value
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("LOG_FILE_MAX_SIZE")); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			errs = append(errs, fmt.Errorf("LOG_FILE_MAX_SIZE '%s' 無效，將使用預設值 %d", value, DefaultMaxSizeMB))
+		} else {
+			config.MaxSizeMB = size
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("LOG_FILE_MAX_BACKUPS")); value != "" {
+		backups, err := strconv.Atoi(value)
+		if err != nil || backups < 0 {
+			errs = append(errs, fmt.Errorf("LOG_FILE_MAX_BACKUPS '%s' 無效，將使用預設值 %d", value, DefaultMaxBackups))
+		} else {
+			config.MaxBackups = backups
+		}
+	}
+	return config, errors.Join(errs...)
+}
+
+// ParseLevel 解析 debug、info、warn、error 等級別名稱
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("未知的 LOG_LEVEL '%s'，可選值為 debug、info、warn、error", value)
+	}
+	return level, nil
+}
+
+// currentFile 是當前寫入的日誌文件，由 Close 關閉
+var (
+	fileMu      sync.Mutex
+	currentFile io.Closer
+)
+
+// Setup 按設定創建日誌處理器並設為 slog 的預設 Logger，標準庫 log 包的輸出也會以 info 級別經過該處理器。
+// 打開日誌文件失敗時仍會設定控制台輸出並返回錯誤。再次調用時關閉之前的日誌文件。
+func Setup(config Config) error {
+	leveler := new(slog.LevelVar)
+	leveler.Set(config.Level)
+	handlers := []slog.Handler{newHandler(os.Stderr, config.Format, leveler)}
+
+	var file *RotatingFile
+	var err error
+	if config.File != "" {
+		file, err = OpenRotatingFile(config.File, int64(config.MaxSizeMB)<<20, config.MaxBackups)
+		if err != nil {
+			err = fmt.Errorf("打開日誌文件失敗: %w", err)
+		} else {
+			handlers = append(handlers, newHandler(file, config.FileFormat, leveler))
+		}
+	}
+
+	var handler slog.Handler = multiHandler(handlers)
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+	slog.SetDefault(slog.New(handler))
+
+	Close()
+	if file != nil {
+		fileMu.Lock()
+		currentFile = file
+		fileMu.Unlock()
+	}
+	return err
+}
+
+// Close 關閉日誌文件，程式退出前調用
+func Close() error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if currentFile == nil {
+		return nil
+	}
+	err := currentFile.Close()
+	currentFile = nil
+	return err
+}
+
+// Component 返回帶 component 屬性的預設 Logger
+func Component(name string) *slog.Logger {
+	return slog.Default().With(KeyComponent, name)
+}
+
+// newHandler 創建指定格式的處理器
+func newHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	options := &slog.HandlerOptions{Level: level}
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, options)
+	case FormatText:
+		return slog.NewTextHandler(w, options)
+	default:
+		return NewConsoleHandler(w, options)
+	}
+}
+
+// multiHandler 將每條日誌分發給所有啟用了該級別的處理器
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, record.Level) {
+			if err := h.Handle(ctx, record.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -3,20 +3,28 @@ package main
 import (
 	ASNIColor "CourseTool/asnicolor"
 	_ "CourseTool/configloader" // Import for side effect: load .env
+	"CourseTool/httpclient"
+	"CourseTool/logging"
 	"CourseTool/sdtbu"
 	"CourseTool/update" // 引入更新檢查包
 	"CourseTool/wxpush"
-	"bufio" // 用於讀取用戶輸入
+	"bufio"   // 用於讀取用戶輸入
+	"context" // 用於取消請求與設定超時
+	"errors"  // 用於判斷錯誤類型
+	"flag"    // 用於解析命令行參數
 	"fmt"
-	"io"       // 用於讀取 HTTP 響應體
-	"log"      // 用於日誌輸出
-	"net/http" // 用於發送 HTTP 請求
-	"os"       // 用於操作環境變數
-	"sort"     // 用於排序時間點
-	"strconv"  // 用於字串轉數字
-	"strings"  // 用於字串處理
-	"sync"     // 用於併發控制 (互斥鎖)
-	"time"     // 用於時間相關操作
+	"io"            // 用於讀取 HTTP 響應體
+	"log/slog"      // 用於日誌輸出
+	"net/http"      // 用於發送 HTTP 請求
+	"os"            // 用於操作環境變數
+	"path/filepath" // 用於構建會話文件路徑
+	"sort"          // 用於排序時間點
+	"strconv"       // 用於字串轉數字
+	"strings"       // 用於字串處理
+	"sync"          // 用於併發控制 (互斥鎖)
+	"time"          // 用於時間相關操作
+
+	"github.com/joho/godotenv" // 用於 /relogin 時重新載入配置
 )
 
 // PushTime 結構體用於儲存推送時間的小時和分鐘
@@ -33,9 +41,6 @@ type SchedulerStatus struct {
 	IsRunning     bool          // 排程器是否正在運行
 }
 
-// globalSchedulerStatus 是排程器狀態的全局實例
-var globalSchedulerStatus = &SchedulerStatus{}
-
 // printBanner 打印應用程式的啟動橫幅
 func printBanner() {
 	fmt.Println(ASNIColor.BrightCyan + `
@@ -58,198 +63,629 @@ GitHub：https://github.com/RichardMiku/CourseTool
 	` + ASNIColor.Reset)
 }
 
-// initializeSession 初始化 SDTBU 客戶端會話並執行登入
-func initializeSession() (*sdtbu.ClientSession, error) {
+// getSession 返回該帳號可複用的門戶會話，首次調用時才初始化。
+// 會話失效時 sdtbu 會在請求中自動重新登入，因此這裡無需檢查會話是否仍然有效。
+func (a *account) getSession(ctx context.Context) (sdtbu.Portal, error) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	if a.session != nil {
+		return a.session, nil
+	}
+	session, err := a.initializeSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.session = session
+	return a.session, nil
+}
+
+// defaultOperationTimeout 是一次推送或控制台命令 (含重試與登入) 的預設總時長
+const defaultOperationTimeout = 10 * time.Minute
+
+// 超時設定：REQUEST_TIMEOUT 限制單個 HTTP 請求，OPERATION_TIMEOUT 限制一次推送或控制台命令的總時長
+var (
+	requestTimeout   = durationFromEnv("REQUEST_TIMEOUT", httpclient.DefaultTimeout)
+	operationTimeout = durationFromEnv("OPERATION_TIMEOUT", defaultOperationTimeout)
+)
+
+// durationFromEnv 讀取 Go duration 格式的環境變數，未設定或無效時返回 fallback
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		logger().Warn(name+" 無效，將使用預設值", "value", value, "default", fallback)
+		return fallback
+	}
+	return duration
+}
+
+// 登入失敗後的暫停時長與推送重試設定
+const (
+	accountLockedBackoff   = time.Hour        // 帳號被鎖定後暫停登入的時長
+	captchaRequiredBackoff = 30 * time.Minute // 需要驗證碼時暫停登入的時長
+	pushRetryAttempts      = 3                // 門戶不可用時每個推送時間點的最大嘗試次數
+	pushRetryDelay         = 2 * time.Minute  // 兩次推送嘗試之間的間隔
+)
+
+// errLoginSuspended 表示因之前的登入失敗而暫停了登入嘗試
+var errLoginSuspended = errors.New("登入已暫停")
+
+// loginGuard 根據登入失敗的原因決定是否暫停後續的登入嘗試，
+// 避免在密碼錯誤或帳號被鎖定時反覆提交登入請求。
+type loginGuard struct {
+	mu             sync.Mutex
+	suspendedUntil time.Time    // 在此時間之前不再嘗試登入
+	permanent      bool         // 為 true 時直到 /relogin 才恢復登入
+	reason         string       // 暫停原因，用於 /status 顯示
+	logger         *slog.Logger // 所屬帳號的日誌
+	configHint     string       // 帳號密碼的設定位置，用於提示用戶修改
+}
+
+// check 在登入被暫停時返回錯誤
+func (g *loginGuard) check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.permanent {
+		return fmt.Errorf("%w: %s，請修改 %s 後輸入 /relogin", errLoginSuspended, g.reason, g.configHint)
+	}
+	if time.Now().Before(g.suspendedUntil) {
+		return fmt.Errorf("%w: %s，將在 %s 後重試", errLoginSuspended, g.reason, g.suspendedUntil.Format("15:04"))
+	}
+	return nil
+}
+
+// observe 根據登入錯誤的類型更新暫停狀態
+func (g *loginGuard) observe(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case errors.Is(err, sdtbu.ErrBadCredentials):
+		g.permanent = true
+		g.reason = "帳號或密碼錯誤"
+		g.logger.Error("=============================================================")
+		g.logger.Error("智慧山商帳號或密碼錯誤，已停止自動登入以免帳號被鎖定！")
+		g.logger.Error("請修改 " + g.configHint + " 後輸入 /relogin。")
+		g.logger.Error("=============================================================")
+	case errors.Is(err, sdtbu.ErrAccountLocked):
+		g.suspendedUntil = time.Now().Add(accountLockedBackoff)
+		g.reason = "帳號已被鎖定"
+		g.logger.Error("智慧山商帳號已被鎖定，將暫停登入。", "until", g.suspendedUntil.Format("15:04"))
+	case errors.Is(err, sdtbu.ErrCaptchaRequired):
+		g.suspendedUntil = time.Now().Add(captchaRequiredBackoff)
+		g.reason = "登入需要驗證碼但未能完成識別"
+		g.logger.Warn("登入需要驗證碼但未能完成識別，將暫停登入，可輸入 /relogin 立即重試。", "until", g.suspendedUntil.Format("15:04"))
+	}
+}
+
+// reset 清除暫停狀態
+func (g *loginGuard) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.suspendedUntil = time.Time{}
+	g.permanent = false
+	g.reason = ""
+}
+
+// status 返回當前的暫停說明，未暫停時返回空字串
+func (g *loginGuard) status() string {
+	if err := g.check(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// initializeSession 初始化該帳號的 SDTBU 客戶端會話，調用方需持有 a.sessionMu。
+// 優先恢復磁碟上保存的會話，沒有可用的保存會話時才執行登入。
+// 使用 -fake-portal 啟動時連接進程內的模擬門戶。
+func (a *account) initializeSession(ctx context.Context) (sdtbu.Portal, error) {
 	sdtbu.Init() // 初始化您的套件
 
 	session, err := sdtbu.NewClientSession()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client session: %v", err)
 	}
+	session.Logger = portalLogger{logger: a.componentLogger("sdtbu")}
+
+	// 配置學期解析器：優先使用覆蓋設定，其次門戶查詢，最後使用本地校曆
+	resolver := &sdtbu.SemesterResolver{CalendarFile: os.Getenv("ACADEMIC_CALENDAR_FILE")}
+	if override := os.Getenv("SEMESTER_OVERRIDE"); override != "" {
+		semester, err := sdtbu.ParseSemesterOverride(override)
+		if err != nil {
+			return nil, fmt.Errorf("解析 SEMESTER_OVERRIDE 失敗: %v", err)
+		}
+		resolver.Override = semester
+	}
+	session.SemesterResolver = resolver
+
+	// 配置門戶訪問方式：直連、WebVPN 或自訂地址
+	mode, err := sdtbu.ParsePortalMode(os.Getenv("PORTAL_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	portal, err := sdtbu.NewPortalResolver(mode, os.Getenv("PORTAL_BASE_URL"), os.Getenv("WEBVPN_HOST"))
+	if err != nil {
+		return nil, err
+	}
+	session.Resolver = portal
 
-	username := os.Getenv("SDTBU_USERNAME")
-	password := os.Getenv("SDTBU_PASSWORD")
+	username, password := a.username, a.password
+	if fakePortal != nil {
+		if session.Resolver, err = fakePortal.PortalResolver(); err != nil {
+			return nil, err
+		}
+		username, password = fakePortal.Username, fakePortal.Password
+	}
+	if portalPlayer != nil && username == "" && password == "" {
+		username, password = "replay", "replay" // 回放不需要真實帳號，登入表單中的憑證已被脫敏
+	}
 
 	if username == "" || password == "" {
-		return nil, fmt.Errorf(ASNIColor.Red + "錯誤: 環境變數 SDTBU_USERNAME 或 SDTBU_PASSWORD 未設定。" + ASNIColor.Reset)
+		return nil, fmt.Errorf("帳號 %s 的學號或密碼未設定 (%s)", a.name, a.guard.configHint)
+	}
+
+	// 配置會話持久化：cookie 加密後保存在磁碟上，跨推送和重啟複用
+	session.SessionFile = a.sessionFile
+	if session.SessionFile == "" {
+		session.SessionFile = filepath.Join("cache", "session-"+username+".bin")
+	}
+	if fakePortal != nil {
+		session.SessionFile = filepath.Join(fakePortalDir, "session-"+a.timetable.key+".bin") // 不覆蓋真實帳號的會話
+	}
+	session.SessionSecret = os.Getenv("SESSION_SECRET")
+	session.CaptchaSolver = newCaptchaSolver()
+	session.SetCredentials(username, password)
+	if err := wrapPortalTransport(session, username, password); err != nil {
+		return nil, err
+	}
+
+	// 錄製與回放時總是重新登入，使錄製包含完整的登入流程
+	if portalRecordDir == "" && portalPlayer == nil {
+		err = session.LoadSession()
+		if err == nil {
+			return session, nil // 已恢復保存的會話，失效時會在請求中自動重新登入
+		}
+		if !errors.Is(err, sdtbu.ErrNoSavedSession) {
+			a.logger.Warn("恢復保存的會話失敗，將重新登入", "err", err)
+		}
 	}
 
-	err = session.Login(username, password)
+	err = session.Login(ctx, username, password)
 	if err != nil {
-		return nil, fmt.Errorf(ASNIColor.Red+"登入失敗: %v"+ASNIColor.Reset, err)
+		return nil, fmt.Errorf("登入失敗: %w", err)
 	}
 
 	return session, nil
 }
 
-// fetchAndProcessClassData 獲取並處理課程數據
-// 返回下一節課的詳細資訊 (map[string]interface{}) 或錯誤
-func fetchAndProcessClassData(session *sdtbu.ClientSession) (map[string]interface{}, error) {
-	// 注意：session 對象在多個 Goroutine 中被訪問。
-	// 如果 sdtbu.ClientSession 的方法（如 GetClassbyUserInfo, GetClassbyTime, ParseClassList, SortClass, NextClass）
-	// 修改了其內部狀態且不是併發安全的，則需要在此處或 sdtbu 內部添加互斥鎖。
-	// 目前假設這些方法是讀取為主或內部已處理併發。
-	session.GetClassbyUserInfo()    // 獲取用戶課程資訊
-	err := session.GetClassbyTime() // 獲取按時間分類的課程資訊
+// timetableStore 保存當前使用的課表，供排程器與控制台命令共用。
+// 課表優先從本地緩存讀取，只有在刷新策略要求時才登入門戶重新獲取。
+type timetableStore struct {
+	mu        sync.Mutex
+	account   *account // 課表所屬的帳號，用於登入門戶與推送課表變化
+	cache     *sdtbu.TimetableCache
+	key       string // 緩存鍵，使用學號區分不同帳號
+	workers   int    // 獲取整個學期課表時的併發數
+	timetable *sdtbu.Timetable
+}
+
+// newTimetableStore 根據環境變數創建帳號的課表存儲，key 為緩存鍵。
+// 使用模擬門戶或回放錄製時緩存保存在臨時目錄中，不覆蓋真實課表。
+func newTimetableStore(acct *account, key string) *timetableStore {
+	cache := &sdtbu.TimetableCache{Dir: os.Getenv("TIMETABLE_CACHE_DIR")}
+	if cache.Dir == "" {
+		cache.Dir = "cache"
+	}
+	if ttlStr := os.Getenv("TIMETABLE_CACHE_TTL"); ttlStr != "" {
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			logger().Warn("TIMETABLE_CACHE_TTL 無效，將使用預設值", "value", ttlStr, "default", sdtbu.DefaultCacheTTL)
+		} else {
+			cache.TTL = ttl
+		}
+	}
+	policy, err := sdtbu.ParseRefreshPolicy(os.Getenv("TIMETABLE_REFRESH_POLICY"))
 	if err != nil {
-		return nil, fmt.Errorf(ASNIColor.Red+"獲取本周課程失敗: %v"+ASNIColor.Reset, err)
+		logger().Warn("將使用預設策略 ttl", "err", err)
+		policy = sdtbu.RefreshIfStale
+	}
+	cache.Policy = policy
+
+	workers, _ := strconv.Atoi(os.Getenv("TIMETABLE_FETCH_WORKERS")) // 無效或未設定時為 0，使用預設值
+
+	switch {
+	case fakePortal != nil:
+		cache.Dir = fakePortalDir
+	case portalPlayer != nil:
+		cache.Dir = portalReplayCacheDir
+		cache.Policy = sdtbu.RefreshAlways
+	case portalRecordDir != "":
+		cache.Policy = sdtbu.RefreshAlways // 每次都從門戶獲取課表，確保錄製包含登入與全部課表請求
+	}
+
+	return &timetableStore{
+		account: acct,
+		cache:   cache,
+		key:     key,
+		workers: workers,
+	}
+}
+
+// Get 返回可用的課表。forceRefresh 為 true 時忽略緩存直接從門戶獲取；
+// 從門戶獲取失敗時，若存在舊的課表則退回使用並打印警告。
+// 重新獲取的課表與上一份課表不同時推送變化摘要 (在釋放鎖之後發送，不阻塞其他查詢)。
+func (ts *timetableStore) Get(ctx context.Context, forceRefresh bool) (*sdtbu.Timetable, error) {
+	timetable, changes, err := ts.get(ctx, forceRefresh)
+	if len(changes) > 0 {
+		ts.account.notifyTimetableChanges(ctx, changes)
 	}
+	return timetable, err
+}
+
+// get 在持有鎖的情況下讀取或刷新課表，並返回與上一份課表相比的變化
+func (ts *timetableStore) get(ctx context.Context, forceRefresh bool) (*sdtbu.Timetable, []sdtbu.TimetableChange, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
 
-	classList, err := session.ParseClassList(session.ClassListbyTimeString) // 解析課程列表
+	if ts.timetable == nil {
+		cached, err := ts.cache.Load(ts.key)
+		if err != nil && !errors.Is(err, sdtbu.ErrCacheMiss) {
+			ts.account.logger.Warn(err.Error())
+		}
+		ts.timetable = cached
+	}
+
+	if !forceRefresh && !ts.cache.NeedsRefresh(ts.timetable, appClock.Now()) {
+		return ts.timetable, nil, nil
+	}
+
+	fetched, err := ts.fetch(ctx)
 	if err != nil {
-		return nil, fmt.Errorf(ASNIColor.Red+"解析課程列表失敗: %v"+ASNIColor.Reset, err)
+		if ts.timetable != nil {
+			ts.account.logger.Warn("刷新課表失敗，將使用緩存課表", "fetched_at", ts.timetable.FetchedAt.Format("2006-01-02 15:04"), "err", err)
+			return ts.timetable, nil, nil
+		}
+		return nil, nil, err
 	}
 
-	sortedClassList, sortMsg := session.SortClass(classList) // 排序課程列表
-	if len(sortedClassList) == 0 {
-		log.Println(ASNIColor.Yellow + "沒有課程可供排序或顯示: " + sortMsg + ASNIColor.Reset)
-		return nil, nil // 返回 nil 表示沒有課程，但不是錯誤
+	if err := ts.cache.Save(ts.key, fetched); err != nil {
+		ts.account.logger.Warn(err.Error())
 	}
+	changes := sdtbu.DiffTimetables(ts.timetable, fetched)
+	ts.timetable = fetched
+	return ts.timetable, changes, nil
+}
+
+// lookaheadDays 是查找下一節課時向後查找的天數，可通過 NEXT_CLASS_LOOKAHEAD_DAYS 設定
+var lookaheadDays = intFromEnv("NEXT_CLASS_LOOKAHEAD_DAYS", sdtbu.DefaultLookaheadDays)
+
+// intFromEnv 讀取非負整數環境變數，未設定或無效時返回 fallback
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger().Warn(name+" 無效，將使用預設值", "value", value, "default", fallback)
+		return fallback
+	}
+	return n
+}
+
+// NextOccurrence 返回從 now 起的下一節課及其日期，跨越週末與教學週向後查找 lookaheadDays 天。
+// 課表中缺少某一週 (例如緩存不完整) 時從門戶獲取該週並更新緩存。
+func (ts *timetableStore) NextOccurrence(ctx context.Context, now time.Time) (*sdtbu.ClassOccurrence, error) {
+	timetable, err := ts.Get(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	fetchedWeeks := 0
+	occurrence, err := timetable.NextOccurrence(ctx, now, lookaheadDays, func(ctx context.Context, week int) ([]sdtbu.Course, error) {
+		session, err := ts.account.getSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ts.account.logger.Info("課表中缺少該週，正在從門戶獲取...", "week", week)
+		courses, err := session.FetchWeek(ctx, &timetable.Semester, week)
+		if err != nil {
+			return nil, err
+		}
+		fetchedWeeks++
+		return courses, nil
+	})
+	if fetchedWeeks > 0 {
+		if err := ts.cache.Save(ts.key, timetable); err != nil {
+			ts.account.logger.Warn(err.Error())
+		}
+	}
+	return occurrence, err
+}
 
-	nextClassInfo, err := session.NextClass(sortedClassList) // 獲取下一節課資訊
+// fetch 使用共用的門戶會話獲取整個學期的課表
+func (ts *timetableStore) fetch(ctx context.Context) (*sdtbu.Timetable, error) {
+	if err := ts.account.guard.check(); err != nil {
+		return nil, err
+	}
+	session, err := ts.account.getSession(ctx)
+	if err != nil {
+		ts.account.guard.observe(err)
+		return nil, err
+	}
+	timetable, err := session.FetchSemester(ctx, ts.workers)
 	if err != nil {
-		log.Printf(ASNIColor.Yellow+"獲取下一節課失敗: %v"+ASNIColor.Reset, err)
+		ts.account.guard.observe(err) // 會話失效後的自動重新登入也可能失敗
+		return nil, fmt.Errorf("獲取學期課表失敗: %w", err)
+	}
+	// 請求過程中門戶可能更新了 cookie，保存最新的會話
+	if err := session.SaveSession(); err != nil {
+		ts.account.logger.Warn("保存會話失敗", "err", err)
+	}
+	ts.account.logger.Info("已獲取課表", "semester", timetable.Semester.String(), "weeks", len(timetable.Weeks))
+	return timetable, nil
+}
+
+// fetchAndProcessClassData 獲取並處理課程數據
+// 返回 now 之後的下一節課及其上課日期 (*sdtbu.ClassOccurrence) 或錯誤；查找範圍內沒有課程時返回 nil
+func (a *account) fetchAndProcessClassData(ctx context.Context, now time.Time) (*sdtbu.ClassOccurrence, error) {
+	occurrence, err := a.timetable.NextOccurrence(ctx, now)
+	if errors.Is(err, sdtbu.ErrNoUpcomingClass) {
+		a.logger.Warn("未來幾天內沒有課程，或未找到下一節課資訊。", "days", lookaheadDays)
 		return nil, nil // 返回 nil 表示沒有下一節課，但不是錯誤
 	}
+	if err != nil {
+		return nil, fmt.Errorf("獲取課表失敗: %w", err)
+	}
+	return occurrence, nil
+}
 
-	if nextClassInfo == nil {
-		log.Println(ASNIColor.Yellow + "今天沒有更多課程了，或未找到下一節課資訊。" + ASNIColor.Reset)
-		return nil, nil // 返回 nil 表示沒有下一節課
+// printCoursesOn 在控制台打印指定日期的課程
+func (a *account) printCoursesOn(ctx context.Context, date time.Time) {
+	timetable, err := a.timetable.Get(ctx, false)
+	if err != nil {
+		fmt.Printf(ASNIColor.Red+"錯誤: 無法獲取課表: %v\n"+ASNIColor.Reset, err)
+		return
 	}
 
-	return nextClassInfo, nil
+	week := timetable.Semester.WeekOf(date)
+	courses := timetable.CoursesOn(date)
+	fmt.Printf(ASNIColor.BrightYellow+"%s (%s 第 %d 週) 的課程："+ASNIColor.Reset+"\n", date.Format("2006-01-02 Mon"), timetable.Semester.String(), week)
+	switch day := sdtbu.ScheduleOn(date); {
+	case day.NoClass:
+		fmt.Printf(ASNIColor.Yellow+"當天是假期 (%s)，停課。\n"+ASNIColor.Reset, day.Reason)
+		return
+	case day.IsMakeup():
+		fmt.Printf(ASNIColor.BrightCyan+"當天調休 (%s)，按 %s 的課表上課。\n"+ASNIColor.Reset, day.Reason, day.Follows.Format("2006-01-02 Mon"))
+	}
+	if len(courses) == 0 {
+		fmt.Println(ASNIColor.Yellow + "當天沒有課程。" + ASNIColor.Reset)
+		return
+	}
+	for i := range courses {
+		courseName, teacherName, location, _ := extractClassInfo(&courses[i], date)
+		timeNumber, err := sdtbu.FormatCourseTime(&courses[i], date)
+		if err != nil {
+			timeNumber = "未知時間"
+		}
+		line := fmt.Sprintf("%s  %s  %s  %s", timeNumber, courseName, teacherName, location)
+		if weeks := courses[i].WeeksLabel(); weeks != "" {
+			line += "  (" + weeks + ")" // 顯示上課週次，方便核對單雙週課程
+		}
+		fmt.Println(line)
+	}
 }
 
-// extractClassInfo 從課程資訊 map 中安全地提取數據
-// 返回課程名稱、教師姓名、地點和時間節次
-func extractClassInfo(classInfo map[string]interface{}) (courseName, teacherName, location, timeNumber string) {
-	var ok bool
+// extractOccurrenceInfo 將下一節課轉換為用於顯示和推送的字符串，不在 now 當天的課程在時間前加上日期
+func extractOccurrenceInfo(occurrence *sdtbu.ClassOccurrence, now time.Time) (courseName, teacherName, location, timeNumber string) {
+	courseName, teacherName, location, timeNumber = extractClassInfo(&occurrence.Course, occurrence.Date)
+	if !occurrence.IsToday(now) {
+		timeNumber = occurrence.Date.Format("01-02 Mon ") + timeNumber
+	}
+	return
+}
 
-	// 提取課程名稱
-	courseName, ok = classInfo["KCMC"].(string)
-	if !ok {
-		log.Println(ASNIColor.Yellow + "警告: 課程名稱 (KCMC) 未在 CLINFO 中找到或其類型非字符串。" + ASNIColor.Reset)
+// extractClassInfo 將課程資訊轉換為用於顯示和推送的字符串
+// 返回課程名稱、教師姓名、地點和時間節次 (按 date 當天生效的作息)，缺失的欄位使用預設值
+func extractClassInfo(course *sdtbu.Course, date time.Time) (courseName, teacherName, location, timeNumber string) {
+	courseName = course.Name
+	if courseName == "" {
 		courseName = "未知課程"
 	}
 
-	// 提取教師姓名，嘗試備用鍵
-	teacherName, ok = classInfo["JSXM"].(string)
-	if !ok {
-		teacherName, ok = classInfo["JSMC"].(string) // 嘗試備用鍵
-		if !ok {
-			log.Println(ASNIColor.Yellow + "警告: 教師姓名 (JSXM/JSMC) 未在 CLINFO 中找到或其類型非字符串。" + ASNIColor.Reset)
-			teacherName = "未知教師"
-		}
+	teacherName = course.Teacher
+	if teacherName == "" {
+		teacherName = "未知教師"
 	}
 
-	// 提取上課地點，嘗試備用鍵
-	location, ok = classInfo["JXDD"].(string)
-	if !ok {
-		location, ok = classInfo["JASMC"].(string) // 嘗試備用鍵
-		if !ok {
-			log.Println(ASNIColor.Yellow + "警告: 上課地點 (JKDD/JASMC) 未在 CLINFO 中找到或其類型非字符串。" + ASNIColor.Reset)
-			location = "未知地點"
-		}
+	location = course.Location
+	if location == "" {
+		location = "未知地點"
 	}
 
-	// 提取上課節次並格式化
-	var skjcVal interface{}
-	skjcVal, ok = classInfo["SKJC"]
-	if !ok {
-		log.Println(ASNIColor.Yellow + "警告: 上課節次 (SKJC) 未在 CLINFO 中找到。" + ASNIColor.Reset)
+	// 根據上課日期生效的作息與上課地點格式化連堂的起止時間
+	var err error
+	timeNumber, err = sdtbu.FormatCourseTime(course, date)
+	if err != nil {
+		logger().Warn("獲取格式化課程時間失敗", "err", err)
 		timeNumber = "未知時間"
-	} else {
-		skjcFloat, ok := skjcVal.(float64)
-		if !ok {
-			log.Printf(ASNIColor.Yellow+"警告: 上課節次 (SKJC) 類型不是 float64，實際為 %T。"+ASNIColor.Reset, skjcVal)
-			timeNumber = "未知時間"
+	}
+	return
+}
+
+// loadHolidayCalendar 根據 HOLIDAY_CALENDAR_FILE 載入假期與調休安排，未設定時每天都按星期上課
+func loadHolidayCalendar() {
+	path := os.Getenv("HOLIDAY_CALENDAR_FILE")
+	if path == "" {
+		return
+	}
+	calendar, err := sdtbu.LoadHolidayCalendar(path)
+	if err != nil {
+		logger().Error("載入假期文件失敗，將不考慮假期與調休", "err", err)
+		return
+	}
+	sdtbu.SetHolidayCalendar(calendar)
+}
+
+// loadBellSchedules 根據環境變數載入作息時間配置與預設連堂節數
+// 未設定 BELL_SCHEDULE_FILE 時使用內置作息
+func loadBellSchedules() {
+	if spanStr := os.Getenv("LESSON_SPAN"); spanStr != "" {
+		span, err := strconv.Atoi(spanStr)
+		if err != nil || span < 1 {
+			logger().Warn("LESSON_SPAN 無效，將使用預設值", "value", spanStr, "default", sdtbu.DefaultLessonSpan)
 		} else {
-			var err error
-			timeNumber, err = sdtbu.GetFormattedClassTime(int(skjcFloat))
-			if err != nil {
-				log.Printf(ASNIColor.Yellow+"警告: 獲取格式化課程時間失敗: %v"+ASNIColor.Reset, err)
-				timeNumber = "未知時間"
-			}
+			sdtbu.DefaultLessonSpan = span
 		}
 	}
-	return
+
+	path := os.Getenv("BELL_SCHEDULE_FILE")
+	if path == "" {
+		return
+	}
+
+	config, err := sdtbu.LoadBellSchedules(path)
+	if err != nil {
+		logger().Error("載入作息時間配置失敗，將使用內置作息", "err", err)
+		return
+	}
+	if campus := os.Getenv("BELL_CAMPUS"); campus != "" {
+		config.Campus = campus // 環境變數優先於配置文件中的校區設定
+	}
+	sdtbu.SetBellSchedules(config)
+	logger().Info("已載入作息時間配置", "path", path, "campus", config.Campus, "schedules", len(config.Schedules))
 }
 
 // fetchNoticeContent 從指定 URL 獲取額外備註內容
-func fetchNoticeContent(url string) string {
-	resp, err := http.Get(url)
+func fetchNoticeContent(ctx context.Context, url string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		logger().Error("無法獲取備註內容", "url", url, "err", err)
+		return "Notice Not Applicable" // 獲取失敗時使用預設內容
+	}
+	resp, err := httpclient.New().Do(req)
 	if err != nil {
-		log.Printf(ASNIColor.Red+"錯誤: 無法從 %s 獲取備註內容: %v"+ASNIColor.Reset, url, err)
+		logger().Error("無法獲取備註內容", "url", url, "err", err)
 		return "Notice Not Applicable" // 獲取失敗時使用預設內容
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf(ASNIColor.Yellow+"警告: 從 %s 獲取備註內容時收到非 200 狀態碼: %d %s"+ASNIColor.Reset, url, resp.StatusCode, resp.Status)
+		logger().Warn("獲取備註內容時收到非 200 狀態碼", "url", url, "status", resp.Status)
 		return "Notice Not Applicable" // 獲取失敗時使用預設內容
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf(ASNIColor.Red+"錯誤: 讀取從 %s 獲取的備註內容失敗: %v"+ASNIColor.Reset, url, err)
+		logger().Error("讀取備註內容失敗", "url", url, "err", err)
 		return "Notice Not Applicable" // 讀取失敗時使用預設內容
 	}
 
 	content := strings.TrimSpace(string(bodyBytes))
 	if content == "" {
-		log.Println(ASNIColor.Yellow + "警告: 從 coursetool.ric.moe/notice 獲取到的內容為空。將使用預設備註。" + ASNIColor.Reset)
+		logger().Warn("從 coursetool.ric.moe/notice 獲取到的內容為空。將使用預設備註。")
 		return "Notice Not Applicable" // 如果內容為空，使用預設內容
 	}
 	return content
 }
 
-// sendWxPushNotification 檢查環境變數並發送微信推送
-func sendWxPushNotification(courseName, teacherName, location, timeNumber string) {
-	wxAppID := os.Getenv("WXPUSH_APP_ID")
-	wxAppSecret := os.Getenv("WXPUSH_APP_SECRET")
-	wxToUser := os.Getenv("WXPUSH_OPEN_ID")
-	wxTemplateID := os.Getenv("WXPUSH_COURSE_TEMPLATE_ID")
+// sendWxPushNotification 獲取額外備註後向該帳號的所有接收者發送課程提醒
+func (a *account) sendWxPushNotification(ctx context.Context, courseName, teacherName, location, timeNumber string) {
+	a.sendCourseReminder(ctx, wxpush.CourseReminderData{
+		CourseName:     courseName,
+		TeacherName:    teacherName,
+		CourseLocation: location,
+		TimeNumber:     timeNumber,
+		Note:           fetchNoticeContent(ctx, "https://coursetool.ric.moe/notice"), // 使用從 URL 獲取的備註
+	})
+}
 
-	// 獲取額外備註內容
-	extraNote := fetchNoticeContent("https://coursetool.ric.moe/notice")
+// wxPushConfigured 檢查微信配置，hasTemplate 判斷接收者是否設定了要使用的模板
+func (a *account) wxPushConfigured(hasTemplate func(wxpush.Recipient) bool) bool {
+	if os.Getenv("WXPUSH_APP_ID") == "" || os.Getenv("WXPUSH_APP_SECRET") == "" || len(a.recipients) == 0 {
+		return false
+	}
+	for _, recipient := range a.recipients {
+		if hasTemplate(recipient) {
+			return true
+		}
+	}
+	return false
+}
 
-	if wxAppID == "" || wxAppSecret == "" || wxToUser == "" || wxTemplateID == "" {
-		log.Println(ASNIColor.Yellow + "警告: 微信推送所需的一個或多個環境變數 (WXPUSH_APP_ID, WXPUSH_APP_SECRET, WXPUSH_OPEN_ID, WXPUSH_COURSE_TEMPLATE_ID) 未設定。將跳過微信推送功能。" + ASNIColor.Reset)
+// sendCourseReminder 使用課程提醒模板向該帳號的所有接收者發送推送，未設定微信推送時打印到控制台
+func (a *account) sendCourseReminder(ctx context.Context, data wxpush.CourseReminderData) {
+	data.NowTime = appClock.Now()
+	if !a.wxPushConfigured(wxpush.Recipient.HasCourseTemplate) {
+		a.logger.Warn("微信推送所需的一個或多個設定 (WXPUSH_APP_ID, WXPUSH_APP_SECRET, 接收者 OpenID, WXPUSH_COURSE_TEMPLATE_ID) 未設定。將跳過微信推送功能。")
+		if len(accounts) > 1 {
+			fmt.Printf(ASNIColor.BrightYellow+"[%s] "+ASNIColor.Reset, a.name)
+		}
 		fmt.Println(ASNIColor.BrightYellow + "下一節課程資訊：" + ASNIColor.Reset)
-		fmt.Printf("課程名稱: %s\n", courseName)
-		fmt.Printf("教師姓名: %s\n", teacherName)
-		fmt.Printf("上課地點: %s\n", location)
-		fmt.Printf("上課節次: %s\n", timeNumber)
-		fmt.Printf("額外備註: %s\n", extraNote) // 顯示從 URL 獲取的備註
+		fmt.Printf("課程名稱: %s\n", data.CourseName)
+		fmt.Printf("教師姓名: %s\n", data.TeacherName)
+		fmt.Printf("上課地點: %s\n", data.CourseLocation)
+		fmt.Printf("上課節次: %s\n", data.TimeNumber)
+		fmt.Printf("額外備註: %s\n", data.Note)
 		return
 	}
 
-	accessToken, err := wxpush.GetAccessToken()
+	accessToken, err := wxpush.GetAccessToken(ctx)
 	if err != nil {
-		// 這裡改為 log.Printf 而不是 log.Fatalf，以便排程器可以繼續運行
-		log.Printf(ASNIColor.Red+"錯誤: 獲取微信 Access Token 失敗: %v"+ASNIColor.Reset, err)
+		// 這裡只記錄錯誤而不是退出程式，以便排程器可以繼續運行
+		a.logger.Error("獲取微信 Access Token 失敗", "err", err)
 		return // 如果獲取 Access Token 失敗，則不繼續發送
 	}
 
-	courseData := wxpush.CourseReminderData{
-		CourseName:     courseName,
-		TeacherName:    teacherName,
-		CourseLocation: location,
-		TimeNumber:     timeNumber,
-		Note:           extraNote, // 使用從 URL 獲取的備註
+	// 逐個發送，某個接收者失敗不影響其他接收者
+	for _, recipient := range a.recipients {
+		if err := wxpush.SendCourseReminderTo(ctx, accessToken, recipient, data); err != nil {
+			a.logger.Error("發送課程提醒失敗", "openid", recipient.OpenID, "err", err)
+		} else {
+			a.logger.Info("課程提醒已成功發送！", "openid", recipient.OpenID)
+		}
 	}
+}
 
-	err = wxpush.SendCourseReminder(accessToken, courseData)
+// sendNotice 使用通知模板向該帳號的所有接收者推送成績、課表變更等非課程消息，不附帶額外備註。
+// 未設定 WXPUSH_NOTICE_TEMPLATE_ID 時只打印到控制台，不會借用課程提醒模板。
+func (a *account) sendNotice(ctx context.Context, notice wxpush.NoticeData) {
+	notice.NowTime = appClock.Now()
+	if !a.wxPushConfigured(wxpush.Recipient.HasNoticeTemplate) {
+		a.logger.Warn("微信推送所需的一個或多個設定 (WXPUSH_APP_ID, WXPUSH_APP_SECRET, 接收者 OpenID, WXPUSH_NOTICE_TEMPLATE_ID) 未設定。將跳過微信推送功能。")
+		if len(accounts) > 1 {
+			fmt.Printf(ASNIColor.BrightYellow+"[%s] "+ASNIColor.Reset, a.name)
+		}
+		fmt.Println(ASNIColor.BrightYellow + notice.Title + ASNIColor.Reset)
+		fmt.Println(notice.Content)
+		if notice.Remark != "" {
+			fmt.Println(notice.Remark)
+		}
+		return
+	}
+
+	accessToken, err := wxpush.GetAccessToken(ctx)
 	if err != nil {
-		fmt.Printf(ASNIColor.Red+"發送課程提醒失敗: %v"+ASNIColor.Reset+"\n", err)
-	} else {
-		fmt.Println(ASNIColor.BrightGreen + "課程提醒已成功發送！" + ASNIColor.Reset)
+		a.logger.Error("獲取微信 Access Token 失敗", "err", err)
+		return
+	}
+
+	for _, recipient := range a.recipients {
+		if err := wxpush.SendNoticeTo(ctx, accessToken, recipient, notice); err != nil {
+			a.logger.Error("發送通知失敗", "openid", recipient.OpenID, "err", err)
+		} else {
+			a.logger.Info("通知已成功發送！", "openid", recipient.OpenID, "title", notice.Title)
+		}
 	}
 }
 
-// parsePushTimeTable 從環境變數中讀取時間表並解析為 PushTime 結構體切片
-func parsePushTimeTable() ([]PushTime, error) {
-	timeTableStr := os.Getenv("PUSH_TIME_TABLE")
+// parsePushTimeTable 將 "HH:MM|HH:MM" 格式的時間表 (PUSH_TIME_TABLE 或帳號的 pushTimes) 解析為 PushTime 結構體切片
+func parsePushTimeTable(timeTableStr string) ([]PushTime, error) {
 	if timeTableStr == "" {
 		return []PushTime{}, nil // 如果未設定，返回空切片
 	}
@@ -288,46 +724,43 @@ func parsePushTimeTable() ([]PushTime, error) {
 	return pushTimes, nil
 }
 
-// runScheduler 負責排程並觸發消息推送
-// 新增 stopChan 參數，用於接收停止訊號
-func runScheduler(stopChan <-chan struct{}) {
-	pushTimes, err := parsePushTimeTable()
-	if err != nil {
-		log.Fatalf(ASNIColor.Red+"錯誤: 解析 PUSH_TIME_TABLE 失敗: %v"+ASNIColor.Reset, err)
-	}
+// runScheduler 負責該帳號的排程並觸發消息推送
+// ctx 被取消 (收到停止訊號) 時退出，並中止進行中的推送請求
+func (a *account) runScheduler(ctx context.Context) {
+	pushTimes := a.pushTimes
 	if len(pushTimes) == 0 {
-		log.Println(ASNIColor.Yellow + "警告: PUSH_TIME_TABLE 未設定或沒有有效時間，排程器將不會觸發推送。" + ASNIColor.Reset)
+		a.logger.Warn("推送時間 (PUSH_TIME_TABLE) 未設定或沒有有效時間，排程器將不會觸發推送。")
 		return
 	}
 
 	// 標記排程器正在運行
-	globalSchedulerStatus.mu.Lock()
-	globalSchedulerStatus.IsRunning = true
-	globalSchedulerStatus.mu.Unlock()
+	a.scheduler.mu.Lock()
+	a.scheduler.IsRunning = true
+	a.scheduler.mu.Unlock()
 
 	// pushedToday 追蹤當天哪些時間點已經推送過，防止重複推送
 	var pushedToday = make(map[string]bool)
-	var lastCheckedDay = time.Now().Day() // 記錄上次檢查的日期，用於每日重置
+	var lastCheckedDay = appClock.Now().Day() // 記錄上次檢查的日期，用於每日重置
 
 	for {
 		select {
-		case <-stopChan: // 如果收到停止訊號
-			log.Println(ASNIColor.BrightYellow + "排程器收到停止訊號，正在退出..." + ASNIColor.Reset)
-			globalSchedulerStatus.mu.Lock()
-			globalSchedulerStatus.IsRunning = false
-			globalSchedulerStatus.mu.Unlock()
+		case <-ctx.Done(): // 如果收到停止訊號
+			a.logger.Info("排程器收到停止訊號，正在退出...")
+			a.scheduler.mu.Lock()
+			a.scheduler.IsRunning = false
+			a.scheduler.mu.Unlock()
 			return // 退出 Goroutine
 		default:
 			// 繼續正常執行
 		}
 
-		now := time.Now()
+		now := appClock.Now()
 
 		// 在午夜時重置 pushedToday map
 		if now.Day() != lastCheckedDay {
 			pushedToday = make(map[string]bool)
 			lastCheckedDay = now.Day()
-			log.Println(ASNIColor.BrightGreen + "已重置每日推送狀態。" + ASNIColor.Reset)
+			a.logger.Info("已重置每日推送狀態。")
 		}
 
 		var nextPushTime time.Time
@@ -338,8 +771,8 @@ func runScheduler(stopChan <-chan struct{}) {
 			scheduledTime := time.Date(now.Year(), now.Month(), now.Day(), pt.Hour, pt.Minute, 0, 0, now.Location())
 			timeStr := fmt.Sprintf("%02d:%02d", pt.Hour, pt.Minute)
 
-			// 如果排程時間在未來並且今天尚未推送
-			if scheduledTime.After(now) && !pushedToday[timeStr] {
+			// 如果排程時間在未來 (或剛過去不到一分鐘，例如從昨天的休眠中醒來時) 並且今天尚未推送
+			if scheduledTime.After(now.Add(-1*time.Minute)) && !pushedToday[timeStr] {
 				nextPushTime = scheduledTime
 				foundNext = true
 				break // 找到最早的下一個時間點，退出循環
@@ -350,120 +783,267 @@ func runScheduler(stopChan <-chan struct{}) {
 			sleepDuration := nextPushTime.Sub(now)
 
 			// 更新全局排程器狀態
-			globalSchedulerStatus.mu.Lock()
-			globalSchedulerStatus.NextPushTime = nextPushTime
-			globalSchedulerStatus.SleepDuration = sleepDuration // 這裡仍然更新，但 /status 將重新計算
-			globalSchedulerStatus.mu.Unlock()
+			a.scheduler.mu.Lock()
+			a.scheduler.NextPushTime = nextPushTime
+			a.scheduler.SleepDuration = sleepDuration // 這裡仍然更新，但 /status 將重新計算
+			a.scheduler.mu.Unlock()
 
-			log.Printf(ASNIColor.BrightBlue+"下一次推送將在 %s 觸發 (剩餘 %s)。"+ASNIColor.Reset, nextPushTime.Format("15:04:05"), sleepDuration)
+			a.logger.Info("等待下一次推送", "at", nextPushTime.Format("15:04:05"), "remaining", sleepDuration.Round(time.Second))
 
 			// 使用 select 監聽停止訊號，同時等待休眠時間
 			select {
-			case <-stopChan:
-				log.Println(ASNIColor.BrightYellow + "排程器收到停止訊號，正在退出..." + ASNIColor.Reset)
-				globalSchedulerStatus.mu.Lock()
-				globalSchedulerStatus.IsRunning = false
-				globalSchedulerStatus.mu.Unlock()
+			case <-ctx.Done():
+				a.logger.Info("排程器收到停止訊號，正在退出...")
+				a.scheduler.mu.Lock()
+				a.scheduler.IsRunning = false
+				a.scheduler.mu.Unlock()
 				return
-			case <-time.After(sleepDuration):
+			case <-appClock.After(sleepDuration):
 				// 休眠時間結束，繼續執行推送邏輯
 			}
 
 			// 喚醒後再次檢查時間，以處理輕微延遲或系統時鐘變化
-			currentCheckTime := time.Now()
+			currentCheckTime := appClock.Now()
 			timeStr := fmt.Sprintf("%02d:%02d", nextPushTime.Hour(), nextPushTime.Minute())
 
 			// 檢查當前時間是否在預定時間附近 (例如 +/- 1 分鐘) 且尚未推送
 			if currentCheckTime.After(nextPushTime.Add(-1*time.Minute)) && currentCheckTime.Before(nextPushTime.Add(1*time.Minute)) && !pushedToday[timeStr] {
-				log.Println(ASNIColor.BrightGreen + "觸發課程推送！" + ASNIColor.Reset)
-
-				// 每次推送前重新初始化會話
-				session, err := initializeSession()
-				if err != nil {
-					log.Printf(ASNIColor.Red+"錯誤: 重新初始化會話失敗，跳過本次推送: %v"+ASNIColor.Reset, err)
-					pushedToday[timeStr] = true // 即使失敗也標記為已嘗試推送，避免無限重試
-					continue
-				}
-
-				// 在推送前獲取最新的課程資訊
-				classInfo, err := fetchAndProcessClassData(session)
-				if err != nil {
-					log.Printf(ASNIColor.Red+"錯誤: 獲取課程資訊失敗: %v"+ASNIColor.Reset, err)
-				} else if classInfo != nil {
-					courseName, teacherName, location, timeNumber := extractClassInfo(classInfo)
-					sendWxPushNotification(courseName, teacherName, location, timeNumber)
-				} else {
-					log.Println(ASNIColor.Yellow + "沒有找到下一節課資訊，跳過推送。" + ASNIColor.Reset)
-				}
+				a.logger.Info("觸發課程推送！")
+				a.push(ctx, currentCheckTime)
 				pushedToday[timeStr] = true // 標記為已推送
 			} else {
-				log.Printf(ASNIColor.Yellow+"警告: 已過預定推送時間 %s 或已推送，跳過本次觸發。", nextPushTime.Format("15:04")+ASNIColor.Reset)
+				a.logger.Warn("已過預定推送時間或已推送，跳過本次觸發。", "at", nextPushTime.Format("15:04"))
 			}
 
 			// 短暫休眠，避免在多個時間點非常接近時導致忙碌等待
-			time.Sleep(1 * time.Second)
+			select {
+			case <-ctx.Done():
+			case <-appClock.After(1 * time.Second):
+			}
 
 		} else {
 			// 今天所有排程時間都已過或已推送。休眠直到明天的第一個排程時間。
-			tomorrow := now.Add(24 * time.Hour)
-			firstPushTimeTomorrow := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), pushTimes[0].Hour, pushTimes[0].Minute, 0, 0, now.Location())
+			// 按日曆日期加一天而不是加 24 小時，夏令時切換當天的一天不是 24 小時
+			firstPushTimeTomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, pushTimes[0].Hour, pushTimes[0].Minute, 0, 0, now.Location())
 			sleepDuration := firstPushTimeTomorrow.Sub(now)
 
 			// 更新全局排程器狀態
-			globalSchedulerStatus.mu.Lock()
-			globalSchedulerStatus.NextPushTime = firstPushTimeTomorrow
-			globalSchedulerStatus.SleepDuration = sleepDuration // 這裡仍然更新，但 /status 將重新計算
-			globalSchedulerStatus.mu.Unlock()
+			a.scheduler.mu.Lock()
+			a.scheduler.NextPushTime = firstPushTimeTomorrow
+			a.scheduler.SleepDuration = sleepDuration // 這裡仍然更新，但 /status 將重新計算
+			a.scheduler.mu.Unlock()
 
-			log.Printf(ASNIColor.BrightBlue+"今天所有推送已完成。將在 %s 重新開始排程 (剩餘 %s)。"+ASNIColor.Reset, firstPushTimeTomorrow.Format("2006-01-02 15:04:05"), sleepDuration)
+			a.logger.Info("今天所有推送已完成，等待明天重新開始排程。", "at", firstPushTimeTomorrow.Format("2006-01-02 15:04:05"), "remaining", sleepDuration.Round(time.Second))
 
 			// 使用 select 監聽停止訊號，同時等待休眠時間
 			select {
-			case <-stopChan:
-				log.Println(ASNIColor.BrightYellow + "排程器收到停止訊號，正在退出..." + ASNIColor.Reset)
-				globalSchedulerStatus.mu.Lock()
-				globalSchedulerStatus.IsRunning = false
-				globalSchedulerStatus.mu.Unlock()
+			case <-ctx.Done():
+				a.logger.Info("排程器收到停止訊號，正在退出...")
+				a.scheduler.mu.Lock()
+				a.scheduler.IsRunning = false
+				a.scheduler.mu.Unlock()
 				return
-			case <-time.After(sleepDuration):
+			case <-appClock.After(sleepDuration):
 				// 休眠時間結束，繼續執行
 			}
 		}
 	}
 }
 
-// handleUserInput 處理用戶在控制台的輸入
-// 新增 stopChan 參數，用於發送停止訊號
-func handleUserInput(stopChan chan<- struct{}) {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Println(ASNIColor.BrightGreen + "排程器已啟動。輸入 /nextcourse 查看下一節課，或輸入 /status 檢查狀態，輸入 /clear 清除控制台，輸入 /stop 退出應用程式。" + ASNIColor.Reset)
-	fmt.Print(ASNIColor.BrightBlue + "> " + ASNIColor.Reset) // 初始提示符
+// push 執行一次排程推送，測試中可以替換為記錄推送時刻的函數
+func (a *account) push(ctx context.Context, now time.Time) {
+	if a.pushFunc != nil {
+		a.pushFunc(ctx, now)
+		return
+	}
+	a.pushNextClass(ctx, now)
+}
 
-	for {
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Printf(ASNIColor.Red+"讀取輸入錯誤: %v\n"+ASNIColor.Reset, err)
-			continue
+// pushNextClass 推送 now 之後的下一節課；下一場考試更早時改為推送考試
+func (a *account) pushNextClass(ctx context.Context, now time.Time) {
+	// 在推送前獲取課程資訊 (課表緩存過期時會自動重新登入獲取)，
+	// 整個推送過程 (含重試) 受 operationTimeout 限制
+	pushCtx, cancelPush := context.WithTimeout(ctx, operationTimeout)
+	defer cancelPush()
+	classInfo, err := a.fetchClassDataWithRetry(pushCtx, now)
+	if err != nil {
+		a.logPushFailure(err)
+	} else if exam := a.nextExamBefore(pushCtx, classInfo, now); exam != nil {
+		// 下一場考試比下一節課更早，推送考試資訊
+		courseName, teacherName, location, timeNumber := extractExamInfo(exam)
+		a.sendWxPushNotification(pushCtx, courseName, teacherName, location, timeNumber)
+	} else if classInfo != nil {
+		courseName, teacherName, location, timeNumber := extractOccurrenceInfo(classInfo, now)
+		a.sendWxPushNotification(pushCtx, courseName, teacherName, location, timeNumber)
+	} else {
+		a.logger.Warn("沒有找到下一節課資訊，跳過推送。")
+	}
+}
+
+// fetchClassDataWithRetry 獲取 now 之後的下一節課資訊。門戶暫時不可用或返回未知頁面時，
+// 間隔 pushRetryDelay 重試，最多嘗試 pushRetryAttempts 次；
+// 帳號相關的錯誤重試也無濟於事，直接返回。
+func (a *account) fetchClassDataWithRetry(ctx context.Context, now time.Time) (*sdtbu.ClassOccurrence, error) {
+	var err error
+	for attempt := 1; attempt <= pushRetryAttempts; attempt++ {
+		var classInfo *sdtbu.ClassOccurrence
+		classInfo, err = a.fetchAndProcessClassData(ctx, now)
+		if err == nil {
+			return classInfo, nil
+		}
+		if !errors.Is(err, sdtbu.ErrPortalUnavailable) && !errors.Is(err, sdtbu.ErrUnexpectedPage) {
+			return nil, err
 		}
+		if attempt == pushRetryAttempts {
+			break
+		}
+		a.logger.Warn("門戶暫時不可用，稍後重試", "attempt", attempt, "max_attempts", pushRetryAttempts, "retry_in", pushRetryDelay, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-appClock.After(pushRetryDelay):
+		}
+	}
+	return nil, err
+}
 
-		command := strings.TrimSpace(input)
+// logPushFailure 根據錯誤類型打印推送失敗的原因
+func (a *account) logPushFailure(err error) {
+	switch {
+	case errors.Is(err, errLoginSuspended):
+		a.logger.Warn("跳過本次推送", "err", err)
+	case errors.Is(err, sdtbu.ErrBadCredentials), errors.Is(err, sdtbu.ErrAccountLocked), errors.Is(err, sdtbu.ErrCaptchaRequired):
+		a.logger.Error("登入失敗，跳過本次推送", "err", err)
+	case errors.Is(err, sdtbu.ErrPortalUnavailable):
+		a.logger.Error("多次重試後門戶仍不可用，跳過本次推送", "err", err)
+	default:
+		a.logger.Error("獲取課程資訊失敗", "err", err)
+	}
+}
 
-		switch strings.ToLower(command) {
-		case "/nextcourse":
-			fmt.Println(ASNIColor.BrightCyan + "正在獲取下一節課程資訊..." + ASNIColor.Reset)
-			// 為 /nextcourse 命令也重新初始化會話，確保其有效性
-			session, err := initializeSession()
+// relogin 清除該帳號的登入暫停狀態並重新載入 CourseTool.env 與帳號文件，下一次獲取課表時會重新登入
+func (a *account) relogin() {
+	if err := godotenv.Overload("CourseTool.env"); err != nil {
+		a.logger.Warn("重新載入 CourseTool.env 失敗", "err", err)
+	}
+	a.reloadCredentials()
+	a.guard.reset()
+
+	a.sessionMu.Lock()
+	a.session = nil // 丟棄舊會話，使用新的帳號密碼重新初始化
+	a.sessionMu.Unlock()
+}
+
+// defaultICSPath 是未指定文件名時導出日曆的預設路徑
+const defaultICSPath = "CourseTool.ics"
+
+// exportTimetableICS 將整個學期的課表導出為 .ics 文件
+func (a *account) exportTimetableICS(ctx context.Context, path string) error {
+	timetable, err := a.timetable.Get(ctx, false)
+	if err != nil {
+		return err
+	}
+	// 考試安排獲取失敗時只導出課程
+	withExams := *timetable
+	if snapshot, err := a.loadExams(ctx, false); err != nil {
+		a.logger.Warn("獲取考試安排失敗，日曆中將不包含考試", "err", err)
+	} else {
+		withExams.Exams = snapshot.Exams
+	}
+	timetable = &withExams
+	if err := sdtbu.WriteICSFile(path, timetable); err != nil {
+		return err
+	}
+	fmt.Printf(ASNIColor.BrightGreen+"已將 %s 的課表導出到 %s，可導入手機或電腦日曆。\n"+ASNIColor.Reset, timetable.Semester.String(), path)
+	return nil
+}
+
+// replHelp 是控制台命令的說明文字
+const replHelp = "輸入 /nextcourse 查看下一節課，輸入 /courses [YYYY-MM-DD] 查看某天的課程，輸入 /exams 查看考試安排，輸入 /grades 查看成績，輸入 /simulate <YYYY-MM-DD HH:MM> 查看某一時刻將推送的內容，輸入 /refresh 重新獲取課表，輸入 /relogin 修改帳號密碼後重新登入，輸入 /exportics [文件名] 導出日曆，輸入 /account [名稱] 查看或切換帳號，或輸入 /status 檢查狀態，輸入 /clear 清除控制台，輸入 /stop 退出應用程式。"
+
+// printSchedulerStatus 打印該帳號排程器的運行狀態與下一次推送時間
+func (a *account) printSchedulerStatus() {
+	a.scheduler.mu.Lock() // 鎖定互斥鎖以安全讀取狀態
+	isRunning := a.scheduler.IsRunning
+	nextTime := a.scheduler.NextPushTime
+	a.scheduler.mu.Unlock() // 解鎖
+
+	prefix := ""
+	if len(accounts) > 1 {
+		prefix = "[" + a.name + "] "
+	}
+	if isRunning {
+		if !nextTime.IsZero() { // 檢查是否有排程時間
+			// 實時計算剩餘時間
+			remainingDuration := nextTime.Sub(appClock.Now())
+			// 輸出格式：(排程器正常運行中，下一次 HH:MM:SS（剩餘 XhYmZs）)
+			fmt.Printf(ASNIColor.BrightGreen+"%s排程器正常運行中，下一次 %s（剩餘 %s）\n"+ASNIColor.Reset,
+				prefix, nextTime.Format("15:04:05"), remainingDuration.Round(time.Second))
+		} else {
+			fmt.Println(ASNIColor.BrightGreen + prefix + "排程器正常運行中，但目前沒有找到下一次觸發時間。" + ASNIColor.Reset)
+		}
+	} else {
+		fmt.Println(ASNIColor.Yellow + prefix + "排程器尚未啟動或已停止。" + ASNIColor.Reset)
+	}
+}
+
+// handleUserInput 處理用戶在控制台的輸入，命令作用於當前選擇的帳號 (預設為 current)
+// 新增 stopChan 參數，用於發送停止訊號；每個命令的網絡請求都受 ctx 與 operationTimeout 限制
+func handleUserInput(ctx context.Context, stopChan chan<- struct{}, current *account) {
+	fmt.Println(ASNIColor.BrightGreen + "排程器已啟動。" + replHelp + ASNIColor.Reset)
+	if len(accounts) > 1 {
+		fmt.Printf(ASNIColor.BrightGreen+"共 %d 個帳號，當前帳號為 %s。\n"+ASNIColor.Reset, len(accounts), current.name)
+	}
+	fmt.Print(ASNIColor.BrightBlue + "> " + ASNIColor.Reset) // 初始提示符
+
+	// 在獨立的協程中讀取輸入，這樣命令觸發登入並等待驗證碼時仍可以接收 /captcha
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			input, err := reader.ReadString('\n')
 			if err != nil {
-				fmt.Printf(ASNIColor.Red+"錯誤: 無法初始化會話以獲取課程資訊: %v\n"+ASNIColor.Reset, err)
+				fmt.Printf(ASNIColor.Red+"讀取輸入錯誤: %v\n"+ASNIColor.Reset, err)
 				continue
 			}
+			if globalCaptchaSolver.offer(input) {
+				continue // 該行已作為驗證碼提交
+			}
+			lines <- input
+		}
+	}()
+	globalCaptchaSolver.interactive.Store(true)
 
-			classInfo, err := fetchAndProcessClassData(session)
+	for input := range lines {
+		cmdCtx, cancelCmd := context.WithTimeout(ctx, operationTimeout)
+		command := strings.TrimSpace(input)
+		fields := strings.Fields(command)
+		var args []string
+		if len(fields) > 1 {
+			args = fields[1:]
+		}
+		name := ""
+		if len(fields) > 0 {
+			name = strings.ToLower(fields[0])
+		}
+
+		switch name {
+		case "/nextcourse":
+			fmt.Println(ASNIColor.BrightCyan + "正在獲取下一節課程資訊..." + ASNIColor.Reset)
+			now := appClock.Now()
+			classInfo, err := current.fetchAndProcessClassData(cmdCtx, now)
 			if err != nil {
 				fmt.Printf(ASNIColor.Red+"錯誤: 無法獲取課程資訊: %v\n"+ASNIColor.Reset, err)
+			} else if exam := current.nextExamBefore(cmdCtx, classInfo, now); exam != nil {
+				courseName, seat, location, timeLabel := extractExamInfo(exam)
+				fmt.Println(ASNIColor.BrightYellow + "下一場考試資訊：" + ASNIColor.Reset)
+				fmt.Printf("考試課程: %s\n", courseName)
+				fmt.Printf("考試時間: %s\n", timeLabel)
+				fmt.Printf("考場: %s\n", location)
+				fmt.Printf("座位: %s\n", seat)
 			} else if classInfo != nil {
-				courseName, teacherName, location, timeNumber := extractClassInfo(classInfo)
-				extraNote := fetchNoticeContent("https://coursetool.ric.moe/notice") // 獲取備註
+				courseName, teacherName, location, timeNumber := extractOccurrenceInfo(classInfo, now)
+				extraNote := fetchNoticeContent(cmdCtx, "https://coursetool.ric.moe/notice") // 獲取備註
 				fmt.Println(ASNIColor.BrightYellow + "下一節課程資訊：" + ASNIColor.Reset)
 				fmt.Printf("課程名稱: %s\n", courseName)
 				fmt.Printf("教師姓名: %s\n", teacherName)
@@ -473,74 +1053,205 @@ func handleUserInput(stopChan chan<- struct{}) {
 			} else {
 				fmt.Println(ASNIColor.Yellow + "沒有找到下一節課資訊。" + ASNIColor.Reset)
 			}
-		case "/status":
-			globalSchedulerStatus.mu.Lock() // 鎖定互斥鎖以安全讀取狀態
-			isRunning := globalSchedulerStatus.IsRunning
-			nextTime := globalSchedulerStatus.NextPushTime
-			globalSchedulerStatus.mu.Unlock() // 解鎖
-
-			if isRunning {
-				if !nextTime.IsZero() { // 檢查是否有排程時間
-					// 實時計算剩餘時間
-					remainingDuration := time.Until(nextTime)
-					// 輸出格式：(排程器正常運行中，下一次 HH:MM:SS（剩餘 XhYmZs）)
-					fmt.Printf(ASNIColor.BrightGreen+"排程器正常運行中，下一次 %s（剩餘 %s）\n"+ASNIColor.Reset,
-						nextTime.Format("15:04:05"), remainingDuration.Round(time.Second))
+		case "/courses":
+			date := appClock.Now()
+			if len(args) > 0 {
+				parsed, err := time.ParseInLocation("2006-01-02", args[0], time.Local)
+				if err != nil {
+					fmt.Printf(ASNIColor.Red+"無效的日期 '%s'，預期格式為 YYYY-MM-DD\n"+ASNIColor.Reset, args[0])
+					break
+				}
+				date = parsed
+			}
+			current.printCoursesOn(cmdCtx, date)
+		case "/exams":
+			current.printUpcomingExams(cmdCtx)
+		case "/simulate":
+			at, err := parseSimulatedTime(strings.Join(args, " "))
+			if err != nil {
+				fmt.Printf(ASNIColor.Red+"%v\n"+ASNIColor.Reset, err)
+				break
+			}
+			current.simulateReminder(cmdCtx, at)
+		case "/grades":
+			fmt.Println(ASNIColor.BrightCyan + "正在獲取成績..." + ASNIColor.Reset)
+			current.printGrades(cmdCtx)
+		case "/refresh":
+			fmt.Println(ASNIColor.BrightCyan + "正在重新獲取整個學期的課表..." + ASNIColor.Reset)
+			timetable, err := current.timetable.Get(cmdCtx, true)
+			if err != nil {
+				fmt.Printf(ASNIColor.Red+"錯誤: 刷新課表失敗: %v\n"+ASNIColor.Reset, err)
+			} else {
+				fmt.Printf(ASNIColor.BrightGreen+"課表已更新 (%s，獲取於 %s)。\n"+ASNIColor.Reset, timetable.Semester.String(), timetable.FetchedAt.Format("2006-01-02 15:04"))
+				if snapshot, err := current.loadExams(cmdCtx, true); err != nil {
+					fmt.Printf(ASNIColor.Red+"錯誤: 刷新考試安排失敗: %v\n"+ASNIColor.Reset, err)
 				} else {
-					fmt.Println(ASNIColor.BrightGreen + "排程器正常運行中，但目前沒有找到下一次觸發時間。" + ASNIColor.Reset)
+					fmt.Printf(ASNIColor.BrightGreen+"考試安排已更新 (共 %d 場)。\n"+ASNIColor.Reset, len(snapshot.Exams))
 				}
+			}
+		case "/relogin":
+			current.relogin()
+			fmt.Println(ASNIColor.BrightCyan + "已重新載入配置，正在重新登入並獲取課表..." + ASNIColor.Reset)
+			if _, err := current.getSession(cmdCtx); err != nil {
+				current.guard.observe(err)
+				fmt.Printf(ASNIColor.Red+"錯誤: 重新登入失敗: %v\n"+ASNIColor.Reset, err)
+			} else if _, err := current.timetable.Get(cmdCtx, true); err != nil {
+				fmt.Printf(ASNIColor.Red+"錯誤: 刷新課表失敗: %v\n"+ASNIColor.Reset, err)
+			} else {
+				fmt.Println(ASNIColor.BrightGreen + "重新登入成功。" + ASNIColor.Reset)
+			}
+		case "/captcha":
+			if globalCaptchaSolver.pending.Load() {
+				fmt.Println(ASNIColor.Yellow + "用法: /captcha <驗證碼>" + ASNIColor.Reset)
 			} else {
-				fmt.Println(ASNIColor.Yellow + "排程器尚未啟動或已停止。" + ASNIColor.Reset)
+				fmt.Println(ASNIColor.Yellow + "目前沒有等待輸入的驗證碼。" + ASNIColor.Reset)
+			}
+		case "/exportics":
+			path := defaultICSPath
+			if len(args) > 0 {
+				path = args[0]
 			}
+			if err := current.exportTimetableICS(cmdCtx, path); err != nil {
+				fmt.Printf(ASNIColor.Red+"錯誤: 導出日曆失敗: %v\n"+ASNIColor.Reset, err)
+			}
+		case "/status":
+			// 多帳號時列出所有帳號的狀態
+			for _, acct := range accounts {
+				acct.printSchedulerStatus()
+				if reason := acct.guard.status(); reason != "" {
+					fmt.Printf(ASNIColor.Red+"%s\n"+ASNIColor.Reset, reason)
+				}
+			}
+		case "/account":
+			if len(args) == 0 {
+				for _, acct := range accounts {
+					marker := "  "
+					if acct == current {
+						marker = "* "
+					}
+					fmt.Printf("%s%s (%s，%d 個推送接收者)\n", marker, acct.name, acct.timetable.key, len(acct.recipients))
+				}
+				break
+			}
+			acct := findAccount(args[0])
+			if acct == nil {
+				fmt.Printf(ASNIColor.Red+"未找到帳號 '%s'，輸入 /account 查看所有帳號。\n"+ASNIColor.Reset, args[0])
+				break
+			}
+			current = acct
+			fmt.Printf(ASNIColor.BrightGreen+"已切換到帳號 %s。\n"+ASNIColor.Reset, current.name)
 		case "/clear": // 處理 /clear 命令
 			// ANSI escape code to clear the screen and move cursor to home
 			fmt.Print("\033[H\033[2J")
 			printBanner() // 清除後重新打印橫幅
-			fmt.Println(ASNIColor.BrightGreen + "控制台已清除。輸入 /nextcourse 查看下一節課，或輸入 /status 檢查狀態，輸入 /clear 清除控制台，輸入 /stop 退出應用程式。" + ASNIColor.Reset)
+			fmt.Println(ASNIColor.BrightGreen + "控制台已清除。" + replHelp + ASNIColor.Reset)
 		case "/stop": // 新增 /stop 命令
 			fmt.Println(ASNIColor.BrightYellow + "正在停止應用程式..." + ASNIColor.Reset)
-			close(stopChan) // 關閉通道，向 runScheduler 發送停止訊號
+			close(stopChan) // 關閉通道，取消根 context 以停止 runScheduler
 			// 給 runScheduler 一點時間來響應停止訊號
 			time.Sleep(500 * time.Millisecond)
 			os.Exit(0) // 退出應用程式
 		case "": // 如果用戶只按了 Enter
-			globalSchedulerStatus.mu.Lock() // 鎖定互斥鎖以安全讀取狀態
-			isRunning := globalSchedulerStatus.IsRunning
-			nextTime := globalSchedulerStatus.NextPushTime
-			globalSchedulerStatus.mu.Unlock() // 解鎖
-
-			if isRunning {
-				if !nextTime.IsZero() { // 檢查是否有排程時間
-					// 實時計算剩餘時間
-					remainingDuration := time.Until(nextTime)
-					fmt.Printf(ASNIColor.BrightGreen+"排程器正常運行中，下一次 %s（剩餘 %s）\n"+ASNIColor.Reset,
-						nextTime.Format("15:04:05"), remainingDuration.Round(time.Second))
-				} else {
-					fmt.Println(ASNIColor.BrightGreen + "排程器正常運行中，但目前沒有找到下一次觸發時間。" + ASNIColor.Reset)
-				}
-			} else {
-				fmt.Println(ASNIColor.Yellow + "排程器尚未啟動或已停止。" + ASNIColor.Reset)
-			}
+			current.printSchedulerStatus()
 		default:
 			fmt.Printf(ASNIColor.Yellow+"未知指令: %s\n"+ASNIColor.Reset, command)
 		}
+		cancelCmd()
 		fmt.Print(ASNIColor.BrightBlue + "> " + ASNIColor.Reset) // 每次處理完畢後再次顯示提示符
 	}
 }
 
 func main() {
+	exportICSPath := flag.String("export-ics", "", "將整個學期的課表導出為 .ics 文件後退出")
+	accountName := flag.String("account", "", "使用 ACCOUNTS_FILE 時 -export-ics 與控制台命令預設使用的帳號 (名稱或學號)")
+	fakePortalMode := flag.String("fake-portal", "", "連接進程內的模擬門戶 (direct 或 webvpn)，用於離線體驗與調試")
+	simulatedNow := flag.String("now", "", "從指定時刻 (YYYY-MM-DD HH:MM) 開始運行，用於模擬排程與提醒")
+	flag.Parse()
+
+	defer logging.Close()
+
+	// sdtbu 本身不打印任何內容，其日誌轉發到 slog，與其他包使用相同的級別與輸出格式
+	sdtbu.SetLogger(portalLogger{logger: logging.Component("sdtbu")})
+
+	// 門戶、微信推送、更新檢查與備註獲取共用同一套代理、證書、超時與 User-Agent 設定
+	if err := httpclient.Configure(httpclient.Config{
+		ProxyURL:       os.Getenv("PROXY_URL"),
+		CAFile:         os.Getenv("CA_BUNDLE_FILE"),
+		Timeout:        requestTimeout,
+		ConnectTimeout: durationFromEnv("CONNECT_TIMEOUT", httpclient.DefaultConnectTimeout),
+		UserAgent:      os.Getenv("USER_AGENT"),
+	}); err != nil {
+		fatal("HTTP 客戶端設定無效", "err", err)
+	}
+
+	if *simulatedNow != "" {
+		if err := setSimulatedNow(*simulatedNow); err != nil {
+			fatal("-now 無效", "err", err)
+		}
+	}
+
+	if *fakePortalMode != "" {
+		stopFakePortal, err := startFakePortal(*fakePortalMode)
+		if err != nil {
+			fatal("啟動模擬門戶失敗", "err", err)
+		}
+		defer stopFakePortal()
+	}
+	stopReplay, err := setupPortalReplay(*simulatedNow == "")
+	if err != nil {
+		fatal(err.Error())
+	}
+	defer stopReplay()
+
+	// 載入帳號，每個帳號有獨立的會話、課表緩存與推送排程
+	accounts, err = loadAccounts()
+	if err != nil {
+		fatal("載入帳號失敗", "err", err)
+	}
+	current := accounts[0]
+	if *accountName != "" {
+		if current = findAccount(*accountName); current == nil {
+			fatal("未找到帳號", "account", *accountName)
+		}
+	}
+
 	// 打印應用程式啟動橫幅
 	printBanner()
 
-	// 調用 update 包中的 CheckForUpdates 函數，檢查應用程式更新
-	update.CheckForUpdates()
+	// 命令行導出模式：導出日曆後直接退出，不啟動排程器
+	if *exportICSPath != "" {
+		loadBellSchedules()
+		loadHolidayCalendar()
+		exportCtx, cancelExport := context.WithTimeout(context.Background(), operationTimeout)
+		defer cancelExport()
+		if err := current.exportTimetableICS(exportCtx, *exportICSPath); err != nil {
+			fatal("導出日曆失敗", "err", err)
+		}
+		return
+	}
 
-	// 創建一個用於停止排程器的通道
+	// 創建應用程式的根 context 與用於停止排程器的通道，
+	// /stop 關閉通道時取消根 context，從而中止所有進行中的請求
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	stopChan := make(chan struct{})
+	go func() {
+		<-stopChan
+		cancel()
+	}()
 
-	// 在一個新的 Goroutine 中啟動排程器，並傳遞停止通道
-	go runScheduler(stopChan)
+	// 調用 update 包中的 CheckForUpdates 函數，檢查應用程式更新
+	update.CheckForUpdates(ctx)
+
+	// 載入作息時間配置與假期安排
+	loadBellSchedules()
+	loadHolidayCalendar()
+
+	// 為每個帳號並行啟動排程器、考試前的專門提醒與成績輪詢
+	for _, acct := range accounts {
+		acct.start(ctx)
+	}
 
 	// 主 Goroutine 處理用戶輸入，並傳遞停止通道
-	handleUserInput(stopChan)
+	handleUserInput(ctx, stopChan, current)
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"CourseTool/sdtbu"
+	"CourseTool/sdtbu/replay"
+	"fmt"
+	"os"
+)
+
+// 門戶流量的錄製與回放：PORTAL_RECORD_DIR 錄製脫敏後的請求與響應，PORTAL_REPLAY_DIR 回放錄製
+var (
+	portalRecordDir      string
+	portalPlayer         *replay.Player
+	portalReplayCacheDir string // 回放時的臨時課表緩存目錄
+)
+
+// setupPortalReplay 根據環境變數啟用錄製或回放。
+// 回放時使用佔位帳號與臨時課表緩存，不需要真實的帳號密碼，也不會覆蓋本地數據。
+// pinClock 為 true (未指定 -now) 時把時鐘固定在錄製時間，使請求中的日期與錄製一致，
+// 此時與錄製不同的請求視為錯誤；返回的函數用於清理臨時目錄。
+func setupPortalReplay(pinClock bool) (func(), error) {
+	recordDir := os.Getenv("PORTAL_RECORD_DIR")
+	replayDir := os.Getenv("PORTAL_REPLAY_DIR")
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, fmt.Errorf("PORTAL_RECORD_DIR 與 PORTAL_REPLAY_DIR 不能同時設定")
+	case recordDir != "":
+		portalRecordDir = recordDir // 之後創建的帳號每次都從門戶獲取課表
+		logger().Info("正在錄製門戶流量，分享前請檢查其中是否仍有個人資訊。", "dir", recordDir)
+		return func() {}, nil
+	case replayDir != "":
+		player, err := replay.LoadPlayer(replayDir)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := os.MkdirTemp("", "coursetool-replay-")
+		if err != nil {
+			return nil, fmt.Errorf("創建回放的臨時目錄失敗: %w", err)
+		}
+		if recordedAt := player.RecordedAt(); pinClock && !recordedAt.IsZero() {
+			startClockAt(recordedAt.Local())
+			player.Strict = true
+			logger().Info("回放時使用錄製時的時間，可以用 -now 指定其他時刻。", "now", recordedAt.Local().Format(simulatedTimeLayout))
+		}
+		portalPlayer, portalReplayCacheDir = player, dir
+		logger().Info("正在回放錄製的門戶流量，不會連接真實門戶。", "dir", replayDir)
+		return func() { os.RemoveAll(dir) }, nil
+	default:
+		return func() {}, nil
+	}
+}
+
+// wrapPortalTransport 在錄製或回放模式下替換會話的 Transport
+func wrapPortalTransport(session *sdtbu.ClientSession, username, password string) error {
+	switch {
+	case portalPlayer != nil:
+		session.Client.Transport = portalPlayer
+		session.SessionFile = "" // 回放的會話沒有意義，不保存
+	case portalRecordDir != "":
+		recorder, err := replay.NewRecorder(portalRecordDir, session.Client.Transport, username, password)
+		if err != nil {
+			return err
+		}
+		session.Client.Transport = recorder
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"CourseTool/clock"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	_ "time/tzdata" // 測試使用有夏令時的時區，不依賴系統的時區數據
+)
+
+// schedulerTest 在 clock.Manual 上運行一個帳號的排程器，記錄每次推送的時刻
+type schedulerTest struct {
+	t       *testing.T
+	clock   *clock.Manual
+	account *account
+	pushes  chan time.Time
+}
+
+// startScheduler 將 appClock 設為從 at 開始的 clock.Manual 並啟動排程器，測試結束時停止排程器並恢復系統時鐘
+func startScheduler(t *testing.T, at time.Time, pushTimes []PushTime) *schedulerTest {
+	t.Helper()
+	manual := clock.NewManual(at)
+	appClock = manual
+	st := &schedulerTest{t: t, clock: manual, pushes: make(chan time.Time, 10)}
+	st.account = &account{
+		name:      "test",
+		pushTimes: pushTimes,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		scheduler: &SchedulerStatus{},
+		pushFunc:  func(ctx context.Context, now time.Time) { st.pushes <- now },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		st.account.runScheduler(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		appClock = clock.System{}
+	})
+	return st
+}
+
+// waitForNextPush 等待排程器開始休眠，返回其預計的下一次推送時間
+func (st *schedulerTest) waitForNextPush() time.Time {
+	st.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for st.clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			st.t.Fatal("排程器沒有開始等待")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	st.account.scheduler.mu.Lock()
+	defer st.account.scheduler.mu.Unlock()
+	return st.account.scheduler.NextPushTime
+}
+
+// expectPush 將時鐘撥到 at 並確認排程器在該時刻推送了一次
+func (st *schedulerTest) expectPush(at time.Time) {
+	st.t.Helper()
+	st.clock.Set(at)
+	select {
+	case pushed := <-st.pushes:
+		if !pushed.Equal(at) {
+			st.t.Errorf("推送時刻 = %s, want %s", pushed, at)
+		}
+	case <-time.After(5 * time.Second):
+		st.t.Fatalf("時鐘撥到 %s 後沒有推送", at)
+	}
+	// 推送後排程器休眠一秒再查找下一個時間點
+	st.waitForNextPush()
+	st.clock.Advance(time.Second)
+}
+
+func TestSchedulerPushesAtEachPushTime(t *testing.T) {
+	day := time.Date(2025, 3, 12, 0, 0, 0, 0, time.Local)
+	st := startScheduler(t, day.Add(6*time.Hour+59*time.Minute), []PushTime{{Hour: 7}, {Hour: 12, Minute: 30}})
+
+	for _, want := range []time.Time{
+		day.Add(7 * time.Hour),
+		day.Add(12*time.Hour + 30*time.Minute),
+	} {
+		if next := st.waitForNextPush(); !next.Equal(want) {
+			t.Fatalf("下一次推送時間 = %s, want %s", next, want)
+		}
+		st.expectPush(want)
+	}
+
+	// 今天的推送都已完成，等待明天的第一個時間點
+	tomorrow := day.AddDate(0, 0, 1).Add(7 * time.Hour)
+	if next := st.waitForNextPush(); !next.Equal(tomorrow) {
+		t.Fatalf("下一次推送時間 = %s, want %s", next, tomorrow)
+	}
+	st.expectPush(tomorrow)
+
+	select {
+	case pushed := <-st.pushes:
+		t.Errorf("多推送了一次: %s", pushed)
+	default:
+	}
+}
+
+func TestSchedulerTomorrowAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-03-09 凌晨兩點進入夏令時，當天只有 23 小時；前一天 23:30 加 24 小時會跳到 03-10
+	st := startScheduler(t, time.Date(2025, 3, 8, 23, 30, 0, 0, newYork), []PushTime{{Hour: 7}})
+
+	want := time.Date(2025, 3, 9, 7, 0, 0, 0, newYork)
+	if next := st.waitForNextPush(); !next.Equal(want) {
+		t.Fatalf("下一次推送時間 = %s, want %s", next, want)
+	}
+	st.expectPush(want)
+}
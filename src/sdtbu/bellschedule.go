@@ -0,0 +1,216 @@
+package sdtbu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BellSchedule 描述一套作息時間表及其適用範圍。
+// 一套作息可以只定義部分節次 (例如某教學樓的晚間節次)，未定義的節次會回退到其他適用的作息。
+type BellSchedule struct {
+	Name      string          `json:"name"`      // 作息名稱，例如 "main-summer"
+	Campus    string          `json:"campus"`    // 適用校區，為空表示所有校區
+	Locations []string        `json:"locations"` // 適用的上課地點關鍵字 (例如教學樓名稱)，為空表示所有地點
+	From      string          `json:"from"`      // 生效開始日期 "MM-DD"，為空表示全年有效
+	To        string          `json:"to"`        // 生效結束日期 "MM-DD" (包含)，允許跨年，例如 "10-01" 至 "04-30"
+	Lessons   []ClassSchedule `json:"lessons"`   // 各節次的開始和結束時間
+}
+
+// BellScheduleConfig 是作息時間配置文件的頂層結構
+type BellScheduleConfig struct {
+	Campus    string         `json:"campus"`    // 當前使用的校區
+	Schedules []BellSchedule `json:"schedules"` // 所有作息時間表
+}
+
+// defaultBellSchedule 是內置的作息時間表，在沒有配置文件或配置文件未覆蓋某節次時使用
+var defaultBellSchedule = BellSchedule{
+	Name: "default",
+	Lessons: []ClassSchedule{
+		{Lesson: 1, Start: "08:00", End: "08:45"},
+		{Lesson: 2, Start: "08:45", End: "09:30"},
+		{Lesson: 3, Start: "09:50", End: "10:35"},
+		{Lesson: 4, Start: "10:35", End: "11:20"},
+		{Lesson: 5, Start: "14:00", End: "14:45"},
+		{Lesson: 6, Start: "14:45", End: "15:30"},
+		{Lesson: 7, Start: "15:50", End: "16:35"},
+		{Lesson: 8, Start: "16:35", End: "17:20"},
+		{Lesson: 9, Start: "19:00", End: "19:45"},
+		{Lesson: 10, Start: "19:45", End: "20:30"},
+		{Lesson: 11, Start: "20:50", End: "21:35"},
+	},
+}
+
+var (
+	bellSchedulesMu sync.RWMutex
+	bellSchedules   = &BellScheduleConfig{}
+)
+
+// LoadBellSchedules 從 JSON 文件中讀取作息時間配置並校驗其中的日期與時間格式
+func LoadBellSchedules(path string) (*BellScheduleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取作息時間文件 %s 失敗: %w", path, err)
+	}
+
+	var config BellScheduleConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("解析作息時間文件 %s 失敗: %w", path, err)
+	}
+
+	for _, schedule := range config.Schedules {
+		if (schedule.From == "") != (schedule.To == "") {
+			return nil, fmt.Errorf("作息 '%s' 必須同時設定 from 和 to", schedule.Name)
+		}
+		for _, md := range []string{schedule.From, schedule.To} {
+			if md == "" {
+				continue
+			}
+			if _, err := time.Parse("01-02", md); err != nil {
+				return nil, fmt.Errorf("作息 '%s' 的日期 '%s' 無效，預期格式為 MM-DD", schedule.Name, md)
+			}
+		}
+		for _, lesson := range schedule.Lessons {
+			start, errStart := time.Parse("15:04", lesson.Start)
+			end, errEnd := time.Parse("15:04", lesson.End)
+			if errStart != nil || errEnd != nil || !end.After(start) {
+				return nil, fmt.Errorf("作息 '%s' 第 %d 節的時間 %s-%s 無效", schedule.Name, lesson.Lesson, lesson.Start, lesson.End)
+			}
+		}
+	}
+
+	return &config, nil
+}
+
+// SetBellSchedules 設定全局使用的作息時間配置，傳入 nil 時恢復為內置作息
+func SetBellSchedules(config *BellScheduleConfig) {
+	if config == nil {
+		config = &BellScheduleConfig{}
+	}
+	bellSchedulesMu.Lock()
+	bellSchedules = config
+	bellSchedulesMu.Unlock()
+}
+
+// appliesOn 判斷作息在指定日期是否生效
+func (bs *BellSchedule) appliesOn(date time.Time) bool {
+	if bs.From == "" {
+		return true
+	}
+	md := date.Format("01-02")
+	if bs.From <= bs.To {
+		return md >= bs.From && md <= bs.To
+	}
+	return md >= bs.From || md <= bs.To // 跨年區間
+}
+
+// appliesAt 判斷作息是否適用於指定上課地點
+func (bs *BellSchedule) appliesAt(location string) bool {
+	if len(bs.Locations) == 0 {
+		return true
+	}
+	for _, keyword := range bs.Locations {
+		if keyword != "" && strings.Contains(location, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// specificity 返回作息的具體程度，用於在多套適用作息中選擇最精確的一套
+func (bs *BellSchedule) specificity() int {
+	score := 0
+	if len(bs.Locations) > 0 {
+		score += 4
+	}
+	if bs.From != "" {
+		score += 2
+	}
+	if bs.Campus != "" {
+		score++
+	}
+	return score
+}
+
+// EffectiveBellSchedules 返回在指定日期與地點適用的作息列表，按具體程度從高到低排列，
+// 內置作息始終位於最後作為後備。
+func EffectiveBellSchedules(date time.Time, location string) []BellSchedule {
+	bellSchedulesMu.RLock()
+	config := bellSchedules
+	bellSchedulesMu.RUnlock()
+
+	var candidates []BellSchedule
+	for _, schedule := range config.Schedules {
+		if schedule.Campus != "" && schedule.Campus != config.Campus {
+			continue
+		}
+		if schedule.appliesOn(date) && schedule.appliesAt(location) {
+			candidates = append(candidates, schedule)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].specificity() > candidates[j].specificity()
+	})
+	return append(candidates, defaultBellSchedule)
+}
+
+// LookupLesson 查找指定日期與地點下某一節課的時間
+func LookupLesson(date time.Time, location string, lessonNumber int) (ClassSchedule, bool) {
+	for _, schedule := range EffectiveBellSchedules(date, location) {
+		for _, lesson := range schedule.Lessons {
+			if lesson.Lesson == lessonNumber {
+				return lesson, true
+			}
+		}
+	}
+	return ClassSchedule{}, false
+}
+
+// CourseTimeRange 返回課程在指定日期的實際開始與結束時刻。
+// 開始時間取開始節次的上課時間，結束時間取結束節次的下課時間；
+// 若作息中沒有定義結束節次 (例如連堂越過當天最後一節)，則退回到最後一個已定義的節次。
+func CourseTimeRange(course *Course, date time.Time) (start, end time.Time, err error) {
+	first, ok := LookupLesson(date, course.Location, course.StartLesson)
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("未找到節次 %d 對應的時間表資訊。", course.StartLesson)
+	}
+	last := first
+	for lesson := course.EndLesson; lesson > course.StartLesson; lesson-- {
+		if schedule, ok := LookupLesson(date, course.Location, lesson); ok {
+			last = schedule
+			break
+		}
+	}
+
+	start, err = clockOn(date, first.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err = clockOn(date, last.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+// FormatCourseTime 返回課程在指定日期的時間描述，例如 "08:00-09:30 (第1-2節)"
+func FormatCourseTime(course *Course, date time.Time) (string, error) {
+	start, end, err := CourseTimeRange(course, date)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s (%s)", start.Format("15:04"), end.Format("15:04"), course.LessonLabel()), nil
+}
+
+// clockOn 將 "HH:MM" 形式的時間與日期合併為完整的時刻
+func clockOn(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("無效的時間 '%s': %w", clock, err)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location()), nil
+}
@@ -0,0 +1,140 @@
+package sdtbu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RefreshPolicy 決定何時從門戶重新獲取課表
+type RefreshPolicy string
+
+const (
+	RefreshIfStale RefreshPolicy = "ttl"    // 緩存超過 TTL 或不包含查詢日期時重新獲取 (預設)
+	RefreshAlways  RefreshPolicy = "always" // 每次都重新獲取，失敗時退回緩存
+	RefreshNever   RefreshPolicy = "never"  // 只使用緩存，僅在緩存不存在時獲取
+)
+
+// DefaultCacheTTL 是未指定 TTL 時課表緩存的有效期
+const DefaultCacheTTL = 12 * time.Hour
+
+// ErrCacheMiss 表示緩存中沒有可用的課表
+var ErrCacheMiss = errors.New("timetable cache miss")
+
+// TimetableCache 將課表以 JSON 文件的形式緩存在本地磁碟上
+type TimetableCache struct {
+	Dir    string        // 緩存目錄
+	TTL    time.Duration // 緩存有效期，<= 0 時使用 DefaultCacheTTL
+	Policy RefreshPolicy // 刷新策略，為空時使用 RefreshIfStale
+}
+
+// ParseRefreshPolicy 將配置中的字符串轉換為 RefreshPolicy
+func ParseRefreshPolicy(value string) (RefreshPolicy, error) {
+	switch policy := RefreshPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return RefreshIfStale, nil
+	case RefreshIfStale, RefreshAlways, RefreshNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("未知的課表刷新策略 '%s'，可選值為 ttl、always、never", value)
+	}
+}
+
+// path 返回指定鍵 (通常為學號) 對應的緩存文件路徑
+func (c *TimetableCache) path(key string) string {
+	return cachePath(c.Dir, "timetable", key)
+}
+
+// cachePath 返回 dir 中 <prefix>-<key>.json 的路徑，key 中的路徑分隔符等字符會被替換
+func cachePath(dir, prefix, key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == '.' {
+			return '_'
+		}
+		return r
+	}, key)
+	return filepath.Join(dir, prefix+"-"+name+".json")
+}
+
+// writeFileAtomic 以 0600 權限寫入文件。先寫入同目錄下的臨時文件再重命名，
+// 避免中途中斷留下損壞的文件，多個帳號同時寫入時也不會共用臨時文件。
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// Load 讀取緩存的課表，文件不存在時返回 ErrCacheMiss
+func (c *TimetableCache) Load(key string) (*Timetable, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取課表緩存失敗: %w", err)
+	}
+
+	var timetable Timetable
+	if err := json.Unmarshal(data, &timetable); err != nil {
+		return nil, fmt.Errorf("解析課表緩存失敗: %w", err)
+	}
+	return &timetable, nil
+}
+
+// Save 將課表寫入緩存。先寫入臨時文件再重命名，避免中途中斷留下損壞的緩存。
+func (c *TimetableCache) Save(key string, timetable *Timetable) error {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return fmt.Errorf("創建緩存目錄失敗: %w", err)
+	}
+
+	data, err := json.MarshalIndent(timetable, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化課表失敗: %w", err)
+	}
+
+	if err := writeFileAtomic(c.path(key), data); err != nil {
+		return fmt.Errorf("寫入課表緩存失敗: %w", err)
+	}
+	return nil
+}
+
+// IsFresh 判斷緩存的課表在指定時間是否仍然可用：未超過 TTL 且學期包含該日期
+func (c *TimetableCache) IsFresh(timetable *Timetable, now time.Time) bool {
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return now.Sub(timetable.FetchedAt) < ttl && timetable.Semester.Contains(now)
+}
+
+// NeedsRefresh 根據刷新策略判斷是否需要從門戶重新獲取課表
+func (c *TimetableCache) NeedsRefresh(timetable *Timetable, now time.Time) bool {
+	if timetable == nil {
+		return true
+	}
+	switch c.Policy {
+	case RefreshAlways:
+		return true
+	case RefreshNever:
+		return false
+	default:
+		return !c.IsFresh(timetable, now)
+	}
+}
@@ -0,0 +1,190 @@
+package sdtbu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// MaxCaptchaAttempts 是驗證碼錯誤時重新識別並提交的最大次數
+const MaxCaptchaAttempts = 3
+
+// CaptchaChallenge 描述登入頁面上的一個驗證碼
+type CaptchaChallenge struct {
+	FieldName   string // 表單中提交驗證碼的字段名，例如 captchaResponse
+	ImageURL    string // 驗證碼圖片的完整 URL
+	Image       []byte // 已下載的驗證碼圖片
+	ContentType string // 圖片的 MIME 類型，例如 image/jpeg
+}
+
+// Extension 根據圖片的 MIME 類型返回文件擴展名
+func (c *CaptchaChallenge) Extension() string {
+	switch {
+	case strings.Contains(c.ContentType, "png"):
+		return ".png"
+	case strings.Contains(c.ContentType, "gif"):
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
+
+// CaptchaSolver 負責識別驗證碼，返回需要提交的驗證碼文字。
+// 實現可以是人工輸入、OCR 或外部打碼服務。
+type CaptchaSolver interface {
+	Solve(ctx context.Context, challenge *CaptchaChallenge) (string, error)
+}
+
+// CaptchaSolverFunc 允許使用普通函數作為 CaptchaSolver
+type CaptchaSolverFunc func(ctx context.Context, challenge *CaptchaChallenge) (string, error)
+
+// Solve 調用函數本身
+func (f CaptchaSolverFunc) Solve(ctx context.Context, challenge *CaptchaChallenge) (string, error) {
+	return f(ctx, challenge)
+}
+
+// CommandCaptchaSolver 調用外部命令識別驗證碼。
+// 驗證碼圖片會寫入臨時文件，文件路徑作為最後一個參數傳給命令，
+// 命令在標準輸出中打印的第一行即為識別結果。
+type CommandCaptchaSolver struct {
+	Command string        // 可執行文件路徑
+	Args    []string      // 位於圖片路徑之前的額外參數
+	Timeout time.Duration // 命令的最長運行時間，0 表示不限制
+}
+
+// Solve 運行外部命令並返回其輸出的驗證碼
+func (s *CommandCaptchaSolver) Solve(ctx context.Context, challenge *CaptchaChallenge) (string, error) {
+	file, err := os.CreateTemp("", "coursetool-captcha-*"+challenge.Extension())
+	if err != nil {
+		return "", fmt.Errorf("創建驗證碼臨時文件失敗: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(challenge.Image); err != nil {
+		file.Close()
+		return "", fmt.Errorf("寫入驗證碼臨時文件失敗: %w", err)
+	}
+	file.Close()
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	args := append(append([]string{}, s.Args...), file.Name())
+	cmd := exec.CommandContext(ctx, s.Command, args...) // 超時或取消時終止命令
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("驗證碼識別命令未完成: %w", ctxErr)
+		}
+		return "", fmt.Errorf("驗證碼識別命令 %s 執行失敗: %w: %s", s.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	answer, _, _ := strings.Cut(stdout.String(), "\n")
+	return strings.TrimSpace(answer), nil
+}
+
+// findCaptchaChallenge 在登入頁面中查找驗證碼輸入框和圖片。
+// 頁面不需要驗證碼時返回 nil；pageURL 用於把圖片的相對地址解析為完整 URL，可以為 nil。
+func findCaptchaChallenge(htmlBody string, pageURL *url.URL) *CaptchaChallenge {
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return nil
+	}
+
+	var fieldName, imageSrc string
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := make(map[string]string, len(n.Attr))
+			for _, attr := range n.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+			switch n.Data {
+			case "input":
+				if fieldName == "" && attrs["type"] != "hidden" &&
+					(isCaptchaName(attrs["name"]) || isCaptchaName(attrs["id"])) {
+					fieldName = attrs["name"]
+					if fieldName == "" {
+						fieldName = attrs["id"]
+					}
+				}
+			case "img":
+				if imageSrc == "" && (isCaptchaName(attrs["id"]) || isCaptchaName(attrs["src"])) {
+					imageSrc = attrs["src"]
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+
+	if fieldName == "" {
+		return nil
+	}
+	challenge := &CaptchaChallenge{FieldName: fieldName, ImageURL: imageSrc}
+	if imageSrc != "" && pageURL != nil {
+		if ref, err := url.Parse(imageSrc); err == nil {
+			challenge.ImageURL = pageURL.ResolveReference(ref).String()
+		}
+	}
+	return challenge
+}
+
+// isCaptchaName 判斷 name、id 或 src 是否指向驗證碼
+func isCaptchaName(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.Contains(lower, "captcha") || strings.Contains(lower, "vcode") || strings.Contains(lower, "checkcode")
+}
+
+// solveCaptcha 下載驗證碼圖片並交給 CaptchaSolver 識別
+func (cs *ClientSession) solveCaptcha(ctx context.Context, challenge *CaptchaChallenge) (string, error) {
+	if cs.CaptchaSolver == nil {
+		return "", fmt.Errorf("登入頁面要求輸入驗證碼，但未配置驗證碼識別方式: %w", ErrCaptchaRequired)
+	}
+	if challenge.ImageURL == "" {
+		return "", fmt.Errorf("登入頁面要求輸入驗證碼，但找不到驗證碼圖片: %w", ErrCaptchaRequired)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", challenge.ImageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("Error creating GET request for captcha: %w", err)
+	}
+	req.Header.Set("User-Agent", cs.UserAgent)
+	resp, err := cs.Client.Do(req)
+	if err != nil {
+		return "", portalUnavailableError("Error downloading captcha image", err)
+	}
+	defer resp.Body.Close()
+	if err := checkPortalStatus("Error downloading captcha image", resp); err != nil {
+		return "", err
+	}
+	challenge.Image, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return "", portalUnavailableError("Error reading captcha image", err)
+	}
+	challenge.ContentType = resp.Header.Get("Content-Type")
+
+	cs.log().Warnf("登入需要驗證碼，正在識別...")
+	answer, err := cs.CaptchaSolver.Solve(ctx, challenge)
+	if err != nil {
+		return "", fmt.Errorf("識別驗證碼失敗: %w: %w", ErrCaptchaRequired, err)
+	}
+	if answer == "" {
+		return "", fmt.Errorf("驗證碼識別結果為空: %w", ErrCaptchaRequired)
+	}
+	return answer, nil
+}
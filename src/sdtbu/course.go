@@ -0,0 +1,310 @@
+package sdtbu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Course 結構體表示一門經過解析的課程，取代原先在各處傳遞的 map[string]interface{}。
+// 所有欄位別名的處理與數據校驗都集中在 ParseCourse 中完成。
+type Course struct {
+	Name        string                 `json:"name"`             // 課程名稱 (KCMC)
+	Teacher     string                 `json:"teacher"`          // 教師姓名 (JSXM/JSMC)
+	Location    string                 `json:"location"`         // 上課地點 (JXDD/JASMC)
+	Weekday     int                    `json:"weekday"`          // 上課星期 (SKXQ，1=星期一 ... 7=星期日)
+	StartLesson int                    `json:"startLesson"`      // 開始節次 (SKJC)
+	EndLesson   int                    `json:"endLesson"`        // 結束節次 (JSJC)，缺失時按節次數或 DefaultLessonSpan 推算
+	Weeks       []WeekRange            `json:"weeks"`            // 上課週次範圍，nil 表示未提供週次資訊，空切片表示不在任何一週上課
+	Remark      string                 `json:"remark,omitempty"` // 附加說明，例如 "明天的首節課程"
+	Raw         map[string]interface{} `json:"raw,omitempty"`    // 門戶返回的原始數據，保留以便調試或讀取未建模的欄位
+}
+
+// WeekRange 表示一段教學週，例如 1-8 週或 1-15 週中的單週
+type WeekRange struct {
+	Start  int        `json:"start"`            // 起始週 (包含)
+	End    int        `json:"end"`              // 結束週 (包含)
+	Parity WeekParity `json:"parity,omitempty"` // 只在單週或雙週上課，零值表示範圍內每週都上課
+}
+
+// WeekParity 限定課程只在單週或雙週上課
+type WeekParity int
+
+const (
+	EveryWeek WeekParity = iota // 每週
+	OddWeeks                    // 單週
+	EvenWeeks                   // 雙週
+)
+
+// Contains 判斷指定教學週是否落在該範圍內並符合單雙週限制
+func (wr WeekRange) Contains(week int) bool {
+	if week < wr.Start || week > wr.End {
+		return false
+	}
+	switch wr.Parity {
+	case OddWeeks:
+		return week%2 == 1
+	case EvenWeeks:
+		return week%2 == 0
+	}
+	return true
+}
+
+// String 返回範圍的顯示文字，例如 "1-8週" 或 "1-15週(單)"
+func (wr WeekRange) String() string {
+	label := strconv.Itoa(wr.Start)
+	if wr.End != wr.Start {
+		label += "-" + strconv.Itoa(wr.End)
+	}
+	label += "週"
+	switch wr.Parity {
+	case OddWeeks:
+		label += "(單)"
+	case EvenWeeks:
+		label += "(雙)"
+	}
+	return label
+}
+
+// MeetsInWeek 判斷課程是否在指定教學週上課，沒有週次資訊 (Weeks 為 nil) 的課程視為每週都上課
+func (c *Course) MeetsInWeek(week int) bool {
+	if c.Weeks == nil {
+		return true
+	}
+	for _, wr := range c.Weeks {
+		if wr.Contains(week) {
+			return true
+		}
+	}
+	return false
+}
+
+// WeeksLabel 返回上課週次的顯示文字，例如 "1-8週,10-16週" 或 "1-15週(單)"，沒有週次資訊時返回空字符串
+func (c *Course) WeeksLabel() string {
+	if c.Weeks == nil {
+		return ""
+	}
+	if len(c.Weeks) == 0 {
+		return "無上課週次"
+	}
+	labels := make([]string, len(c.Weeks))
+	for i, wr := range c.Weeks {
+		labels[i] = wr.String()
+	}
+	return strings.Join(labels, ",")
+}
+
+// coursesMeetingIn 從課程列表中篩選出在指定教學週上課的課程，保持原有順序
+func coursesMeetingIn(courses []Course, week int) []Course {
+	var result []Course
+	for _, course := range courses {
+		if course.MeetsInWeek(week) {
+			result = append(result, course)
+		}
+	}
+	return result
+}
+
+// DefaultLessonSpan 是門戶未提供結束節次或節次數時假定的連堂節數。
+// 預設為 1，即結束節次等於開始節次，避免把單節課誤判為連堂；確定學校總是兩節連上時可通過 LESSON_SPAN 修改。
+var DefaultLessonSpan = 1
+
+// 各欄位在門戶不同接口中可能使用的鍵名，按優先順序排列
+var (
+	courseNameKeys     = []string{"KCMC", "KCM", "courseName"}
+	courseTeacherKeys  = []string{"JSXM", "JSMC", "SKJS", "teacherName"}
+	courseLocationKeys = []string{"JXDD", "JASMC", "SKDD", "classroom"}
+	courseWeekdayKeys  = []string{"SKXQ", "XQJ", "weekday"}
+	courseStartKeys    = []string{"SKJC", "KSJC", "startLesson"}
+	courseEndKeys      = []string{"JSJC", "endLesson"}
+	courseSpanKeys     = []string{"JCS", "SKJCS", "lessonCount"}
+	courseWeeksKeys    = []string{"SKZC", "ZCMC", "weeks"}
+)
+
+// ParseCourse 將門戶返回的單個課程物件轉換為 Course 結構體。
+// 上課星期 (1-7) 與開始節次 (>0) 為必填欄位，缺失或無效時返回錯誤；
+// 其餘欄位缺失時保留零值，由調用方決定如何顯示。
+func ParseCourse(raw map[string]interface{}) (Course, error) {
+	course := Course{
+		Name:     lookupString(raw, courseNameKeys),
+		Teacher:  lookupString(raw, courseTeacherKeys),
+		Location: lookupString(raw, courseLocationKeys),
+		Raw:      raw,
+	}
+
+	weekday, ok := lookupInt(raw, courseWeekdayKeys)
+	if !ok || weekday < 1 || weekday > 7 {
+		return Course{}, fmt.Errorf("課程 '%s' 的上課星期 (SKXQ) 無效或缺失: %v", course.Name, lookupValue(raw, courseWeekdayKeys))
+	}
+	course.Weekday = weekday
+
+	start, end, ok := lookupLessonRange(raw, courseStartKeys)
+	if !ok || start < 1 {
+		return Course{}, fmt.Errorf("課程 '%s' 的上課節次 (SKJC) 無效或缺失: %v", course.Name, lookupValue(raw, courseStartKeys))
+	}
+	course.StartLesson = start
+
+	// 結束節次的來源優先順序：節次範圍 ("1-2") -> 結束節次欄位 -> 節次數欄位 -> DefaultLessonSpan
+	if e, ok := lookupInt(raw, courseEndKeys); end == start && ok && e >= start {
+		end = e
+	} else if n, ok := lookupInt(raw, courseSpanKeys); end == start && ok && n >= 1 {
+		end = start + n - 1
+	} else if end == start && DefaultLessonSpan > 1 {
+		end = start + DefaultLessonSpan - 1
+	}
+	course.EndLesson = end
+
+	if spec := lookupString(raw, courseWeeksKeys); spec != "" {
+		weeks, err := ParseWeekSpec(spec)
+		if err != nil {
+			return Course{}, err
+		}
+		course.Weeks = weeks
+	}
+
+	return course, nil
+}
+
+// Span 返回課程佔用的節數
+func (c *Course) Span() int {
+	if c.EndLesson < c.StartLesson {
+		return 1
+	}
+	return c.EndLesson - c.StartLesson + 1
+}
+
+// LessonLabel 返回節次的顯示文字，例如 "第1-2節" 或 "第11節"
+func (c *Course) LessonLabel() string {
+	if c.Span() == 1 {
+		return fmt.Sprintf("第%d節", c.StartLesson)
+	}
+	return fmt.Sprintf("第%d-%d節", c.StartLesson, c.EndLesson)
+}
+
+// minWeekBitmapLength 是 "0/1" 位串的最短長度 (一個學期的週數)，更短的純數字描述 (例如 "10"、"1") 按週次解析
+const minWeekBitmapLength = 16
+
+// ParseWeekSpec 解析課程的上課週次描述。
+// 支持兩種格式：門戶常用的 "0/1" 位串 (第 n 個字符代表第 n 週，長度至少為 minWeekBitmapLength)，
+// 以及文字格式，例如 "1-8,10,12-16周"、"1-15周(单),2-16双周"；單雙週標記只作用於其所在的範圍。
+// 描述為空時返回 nil (未提供週次資訊)；全為 0 的位串返回空切片，表示不在任何一週上課。
+func ParseWeekSpec(spec string) ([]WeekRange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	if len(spec) >= minWeekBitmapLength && strings.Trim(spec, "01") == "" {
+		ranges := []WeekRange{}
+		for i, ch := range spec {
+			if ch != '1' {
+				continue
+			}
+			week := i + 1
+			if n := len(ranges); n > 0 && ranges[n-1].End == week-1 {
+				ranges[n-1].End = week
+			} else {
+				ranges = append(ranges, WeekRange{Start: week, End: week})
+			}
+		}
+		return ranges, nil
+	}
+
+	cleaned := strings.NewReplacer("周", "", "週", "", "第", "", "，", ",", "、", ",", " ", "").Replace(spec)
+	var ranges []WeekRange
+	for _, part := range strings.Split(cleaned, ",") {
+		parity := EveryWeek
+		switch {
+		case strings.ContainsAny(part, "单單"):
+			parity = OddWeeks
+		case strings.ContainsAny(part, "双雙"):
+			parity = EvenWeeks
+		}
+		part = weekParityMarks.Replace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("無法解析週次描述 '%s': %w", spec, err)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("無法解析週次描述 '%s': %w", spec, err)
+			}
+		}
+		if start < 1 || end < start {
+			return nil, fmt.Errorf("週次描述 '%s' 中的範圍 '%s' 無效", spec, part)
+		}
+		ranges = append(ranges, WeekRange{Start: start, End: end, Parity: parity})
+	}
+	return ranges, nil
+}
+
+// weekParityMarks 移除週次描述中的單雙週標記與括號
+var weekParityMarks = strings.NewReplacer("单", "", "單", "", "双", "", "雙", "", "(", "", ")", "", "（", "", "）", "")
+
+// lookupValue 按順序查找第一個存在的鍵並返回其原始值
+func lookupValue(raw map[string]interface{}, keys []string) interface{} {
+	for _, key := range keys {
+		if val, ok := raw[key]; ok && val != nil {
+			return val
+		}
+	}
+	return nil
+}
+
+// lookupLessonRange 查找節次欄位，支持數字 (3) 和範圍字符串 ("3-4"、"3-4節") 兩種形式。
+// 當值為單個數字時 end 與 start 相同。
+func lookupLessonRange(raw map[string]interface{}, keys []string) (start, end int, ok bool) {
+	if n, ok := lookupInt(raw, keys); ok {
+		return n, n, true
+	}
+	spec := strings.NewReplacer("第", "", "節", "", "节", "", " ", "").Replace(lookupString(raw, keys))
+	bounds := strings.SplitN(spec, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, false
+	}
+	start, errStart := strconv.Atoi(bounds[0])
+	end, errEnd := strconv.Atoi(bounds[1])
+	if errStart != nil || errEnd != nil || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// lookupString 按順序查找第一個非空的字符串值
+func lookupString(raw map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		switch v := raw[key].(type) {
+		case string:
+			if s := strings.TrimSpace(v); s != "" {
+				return s
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// lookupInt 按順序查找第一個可轉換為整數的值。
+// 處理 JSON 數字被解析為 float64 以及數字以字符串形式返回的情況。
+func lookupInt(raw map[string]interface{}, keys []string) (int, bool) {
+	for _, key := range keys {
+		switch v := raw[key].(type) {
+		case float64:
+			return int(v), true
+		case int:
+			return v, true
+		case string:
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
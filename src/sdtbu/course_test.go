@@ -0,0 +1,95 @@
+package sdtbu_test
+
+import (
+	"CourseTool/sdtbu"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseWeekSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []sdtbu.WeekRange
+	}{
+		{"", nil},
+		{"1-8,10,12-16周", []sdtbu.WeekRange{{Start: 1, End: 8}, {Start: 10, End: 10}, {Start: 12, End: 16}}},
+		{"1-15周(单),2-16双周", []sdtbu.WeekRange{{Start: 1, End: 15, Parity: sdtbu.OddWeeks}, {Start: 2, End: 16, Parity: sdtbu.EvenWeeks}}},
+		// 短於一個學期的純數字描述按週次解析，而不是位串
+		{"10", []sdtbu.WeekRange{{Start: 10, End: 10}}},
+		{"1", []sdtbu.WeekRange{{Start: 1, End: 1}}},
+		{"11110000111100000000", []sdtbu.WeekRange{{Start: 1, End: 4}, {Start: 9, End: 12}}},
+		{"0000000000000000", []sdtbu.WeekRange{}},
+	}
+	for _, tt := range tests {
+		got, err := sdtbu.ParseWeekSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseWeekSpec(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseWeekSpec(%q) = %#v, want %#v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseWeekSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"0", "8-1周", "abc"} {
+		if _, err := sdtbu.ParseWeekSpec(spec); err == nil {
+			t.Errorf("ParseWeekSpec(%q) returned no error", spec)
+		}
+	}
+}
+
+func TestMeetsInWeekAllZeroBitmap(t *testing.T) {
+	weeks, err := sdtbu.ParseWeekSpec("00000000000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	course := sdtbu.Course{Name: "停開課程", Weekday: 1, StartLesson: 1, EndLesson: 2, Weeks: weeks}
+	for week := 1; week <= 20; week++ {
+		if course.MeetsInWeek(week) {
+			t.Fatalf("全零位串的課程在第 %d 週上課", week)
+		}
+	}
+
+	// 經過課表緩存的 JSON 往返後仍然不上課
+	data, err := json.Marshal(course)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cached sdtbu.Course
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatal(err)
+	}
+	if cached.MeetsInWeek(1) {
+		t.Errorf("緩存後的課程在第 1 週上課: %s", data)
+	}
+
+	if noInfo := (sdtbu.Course{}); !noInfo.MeetsInWeek(5) {
+		t.Error("沒有週次資訊的課程應每週上課")
+	}
+}
+
+func TestParseCourseLessonSpan(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantEnd int
+	}{
+		{"單節課", map[string]interface{}{"KCMC": "形勢與政策", "SKXQ": "2", "SKJC": "5"}, 5},
+		{"結束節次", map[string]interface{}{"KCMC": "高等數學", "SKXQ": "1", "SKJC": "1", "JSJC": "2"}, 2},
+		{"節次數", map[string]interface{}{"KCMC": "體育", "SKXQ": "5", "SKJC": "3", "JCS": "2"}, 4},
+		{"節次範圍", map[string]interface{}{"KCMC": "大學英語", "SKXQ": "2", "SKJC": "3-4"}, 4},
+	}
+	for _, tt := range tests {
+		course, err := sdtbu.ParseCourse(tt.raw)
+		if err != nil {
+			t.Errorf("%s: ParseCourse error: %v", tt.name, err)
+			continue
+		}
+		if course.EndLesson != tt.wantEnd {
+			t.Errorf("%s: EndLesson = %d, want %d", tt.name, course.EndLesson, tt.wantEnd)
+		}
+	}
+}
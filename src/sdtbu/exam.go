@@ -0,0 +1,207 @@
+package sdtbu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExamWidget 是門戶中查詢考試安排的 widgets 接口名稱
+var ExamWidget = "getExamArrangement"
+
+// Exam 表示一場考試安排
+type Exam struct {
+	Course   string                 `json:"course"`         // 課程名稱 (KCMC)
+	Start    time.Time              `json:"start"`          // 開始時間
+	End      time.Time              `json:"end"`            // 結束時間
+	Location string                 `json:"location"`       // 考場 (KSDD/JASMC)
+	Seat     string                 `json:"seat,omitempty"` // 座位號 (ZWH)
+	Raw      map[string]interface{} `json:"raw,omitempty"`  // 門戶返回的原始數據
+}
+
+// 考試安排各欄位在門戶中可能使用的鍵名，按優先順序排列
+var (
+	examCourseKeys   = []string{"KCMC", "KCM", "courseName"}
+	examDateKeys     = []string{"KSRQ", "examDate"}
+	examTimeKeys     = []string{"KSSJ", "KSSJD", "examTime"}
+	examStartKeys    = []string{"KSKSSJ", "startTime"}
+	examEndKeys      = []string{"KSJSSJ", "endTime"}
+	examLocationKeys = []string{"KSDD", "JASMC", "examRoom"}
+	examSeatKeys     = []string{"ZWH", "seatNo"}
+)
+
+// Key 返回考試的唯一標識，用於記錄提醒是否已發送
+func (e *Exam) Key() string {
+	return e.Course + "@" + e.Start.Format("2006-01-02T15:04")
+}
+
+// TimeLabel 返回考試時間的顯示文字，例如 "2025-06-20 09:00-11:00"
+func (e *Exam) TimeLabel() string {
+	return e.Start.Format("2006-01-02 15:04") + "-" + e.End.Format("15:04")
+}
+
+// ParseExam 將門戶返回的單個考試安排轉換為 Exam。
+// 時間可以是開始與結束兩個完整時間，也可以是日期加 "09:00-11:00" 形式的時間段。
+func ParseExam(raw map[string]interface{}) (Exam, error) {
+	exam := Exam{
+		Course:   lookupString(raw, examCourseKeys),
+		Location: lookupString(raw, examLocationKeys),
+		Seat:     lookupString(raw, examSeatKeys),
+		Raw:      raw,
+	}
+
+	start, end, err := parseExamTime(raw)
+	if err != nil {
+		return Exam{}, fmt.Errorf("考試 '%s' 的時間無效: %w", exam.Course, err)
+	}
+	exam.Start, exam.End = start, end
+	return exam, nil
+}
+
+// parseExamTime 解析考試的開始與結束時間
+func parseExamTime(raw map[string]interface{}) (start, end time.Time, err error) {
+	if startStr := lookupString(raw, examStartKeys); startStr != "" {
+		if start, err = parseExamDateTime(startStr); err != nil {
+			return
+		}
+		end, err = parseExamDateTime(lookupString(raw, examEndKeys))
+		return
+	}
+
+	date := lookupString(raw, examDateKeys)
+	period := lookupString(raw, examTimeKeys)
+	if fields := strings.Fields(period); len(fields) == 2 {
+		if date == "" {
+			date = fields[0] // "2025-06-20 09:00-11:00"
+		}
+		period = fields[1]
+	}
+	startClock, endClock, ok := strings.Cut(strings.NewReplacer("~", "-", "－", "-", "至", "-").Replace(period), "-")
+	if date == "" || !ok {
+		err = fmt.Errorf("缺少考試日期或時間段: %v %v", lookupValue(raw, examDateKeys), lookupValue(raw, examTimeKeys))
+		return
+	}
+	if start, err = parseExamDateTime(date + " " + strings.TrimSpace(startClock)); err != nil {
+		return
+	}
+	end, err = parseExamDateTime(date + " " + strings.TrimSpace(endClock))
+	return
+}
+
+// parseExamDateTime 解析門戶中常見的日期時間格式
+func parseExamDateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, "/", "-"))
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-1-2 15:04", "20060102 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("無法解析時間 '%s'", value)
+}
+
+// FetchExams 獲取指定學期的考試安排，按開始時間排序。
+// 會話失效時自動重新登入後重試一次。
+func (cs *ClientSession) FetchExams(ctx context.Context, semester *Semester) ([]Exam, error) {
+	var bodyBytes []byte
+	err := cs.withRelogin(ctx, func() error {
+		var err error
+		bodyBytes, err = cs.postWidget(ctx, ExamWidget, map[string]string{
+			"schoolYear": semester.SchoolYear,
+			"semester":   semester.Term,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rawExams []map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &rawExams); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s response: %w", ExamWidget, err)
+	}
+
+	exams := make([]Exam, 0, len(rawExams))
+	for _, raw := range rawExams {
+		exam, err := ParseExam(raw)
+		if err != nil {
+			cs.log().Warnf("跳過無效的考試安排: %v", err)
+			continue
+		}
+		exams = append(exams, exam)
+	}
+	sort.SliceStable(exams, func(i, j int) bool { return exams[i].Start.Before(exams[j].Start) })
+	return exams, nil
+}
+
+// ExamSnapshot 是某次獲取的考試安排與已發送的考試提醒。
+// 考試安排通常在學期後段才發布且可能調整，因此與課表分開保存並按自己的間隔刷新。
+type ExamSnapshot struct {
+	Exams         []Exam    `json:"exams"`                   // 按開始時間排序
+	FetchedAt     time.Time `json:"fetchedAt"`               // 從門戶獲取的時間
+	SentReminders []string  `json:"sentReminders,omitempty"` // 已發送的提醒標識，重啟後不會重複發送
+}
+
+// ExamStore 將考試安排與已發送的提醒以 JSON 文件的形式保存在本地磁碟上
+type ExamStore struct {
+	Dir string // 保存目錄，通常與課表緩存相同
+}
+
+// Load 讀取保存的考試安排，文件不存在時返回 ErrCacheMiss
+func (s *ExamStore) Load(key string) (*ExamSnapshot, error) {
+	data, err := os.ReadFile(cachePath(s.Dir, "exams", key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取考試安排失敗: %w", err)
+	}
+
+	var snapshot ExamSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析考試安排失敗: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save 寫入考試安排與已發送的提醒
+func (s *ExamStore) Save(key string, snapshot *ExamSnapshot) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("創建緩存目錄失敗: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化考試安排失敗: %w", err)
+	}
+	if err := writeFileAtomic(cachePath(s.Dir, "exams", key), data); err != nil {
+		return fmt.Errorf("寫入考試安排失敗: %w", err)
+	}
+	return nil
+}
+
+// Upcoming 返回尚未結束的考試，按開始時間排序
+func (s *ExamSnapshot) Upcoming(now time.Time) []Exam {
+	var exams []Exam
+	for _, exam := range s.Exams {
+		if now.Before(exam.End) {
+			exams = append(exams, exam)
+		}
+	}
+	return exams
+}
+
+// Next 返回下一場尚未開始的考試，沒有時返回 nil
+func (s *ExamSnapshot) Next(now time.Time) *Exam {
+	for i := range s.Exams {
+		if now.Before(s.Exams[i].Start) {
+			exam := s.Exams[i]
+			return &exam
+		}
+	}
+	return nil
+}
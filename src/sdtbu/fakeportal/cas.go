@@ -0,0 +1,111 @@
+package fakeportal
+
+import (
+	"CourseTool/des"
+	"CourseTool/sdtbu"
+	"html/template"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// loginPage 是模擬的 CAS 登入頁面，字段與真實頁面一致
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>统一身份认证</title></head>
+<body>
+<form id="loginForm" method="post">
+  <input id="un" type="text" placeholder="用户名">
+  <input id="pd" type="password" placeholder="密码">
+  {{if .Captcha}}<input id="captcha" name="captchaResponse" type="text">
+  <img id="captchaImg" src="captcha.jpg">{{end}}
+  <input type="hidden" id="rsa" name="rsa">
+  <input type="hidden" id="ul" name="ul">
+  <input type="hidden" id="pl" name="pl">
+  <input type="hidden" id="lt" name="lt" value="{{.LT}}">
+  <input type="hidden" name="execution" value="e1s1">
+  <input type="hidden" name="_eventId" value="submit">
+  {{if .Error}}<span id="errormsg">{{.Error}}</span>{{end}}
+</form>
+</body>
+</html>`))
+
+// captchaImage 是返回給客戶端的驗證碼圖片 (內容無關緊要，只需是圖片)
+var captchaImage = []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0xff, 0xd9}
+
+// handleLogin 顯示登入頁面或校驗提交的登入表單
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.renderLogin(w, "")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lt := r.PostForm.Get("lt")
+	s.mu.Lock()
+	validLT := s.lts[lt]
+	delete(s.lts, lt) // lt 只能使用一次
+	locked := s.LockAfter > 0 && s.failures >= s.LockAfter
+	s.mu.Unlock()
+
+	switch {
+	case !validLT || r.PostForm.Get("execution") != "e1s1" || r.PostForm.Get("_eventId") != "submit":
+		s.renderLogin(w, "") // 表單已過期，重新顯示登入頁面
+	case locked:
+		s.renderLogin(w, "账号已被锁定，请稍后再试")
+	case s.CaptchaAnswer != "" && r.PostForm.Get("captchaResponse") != s.CaptchaAnswer:
+		s.recordFailure()
+		s.renderLogin(w, "验证码错误")
+	case !s.checkCredentials(r, lt):
+		s.recordFailure()
+		s.renderLogin(w, "用户名或密码错误")
+	default:
+		s.mu.Lock()
+		s.failures = 0
+		s.logins++
+		ticket := s.newID("ST")
+		s.tickets[ticket] = true
+		s.mu.Unlock()
+		http.Redirect(w, r, s.external(sdtbu.DefaultPortalURL+"?ticket="+ticket), http.StatusFound)
+	}
+}
+
+// checkCredentials 按真實 CAS 的規則校驗 rsa、ul 與 pl：
+// rsa 為 des.StrEnc(學號+密碼+lt, "1", "2", "3")，ul 與 pl 為學號與密碼的字符數
+func (s *Server) checkCredentials(r *http.Request, lt string) bool {
+	expected := des.StrEnc(s.Username+s.Password+lt, "1", "2", "3")
+	return r.PostForm.Get("rsa") == expected &&
+		r.PostForm.Get("ul") == strconv.Itoa(utf8.RuneCountInString(s.Username)) &&
+		r.PostForm.Get("pl") == strconv.Itoa(utf8.RuneCountInString(s.Password))
+}
+
+// recordFailure 記錄一次登入失敗
+func (s *Server) recordFailure() {
+	s.mu.Lock()
+	s.failures++
+	s.mu.Unlock()
+}
+
+// renderLogin 發出新的 lt 並顯示登入頁面
+func (s *Server) renderLogin(w http.ResponseWriter, errMsg string) {
+	s.mu.Lock()
+	lt := s.newID("LT")
+	s.lts[lt] = true
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginPage.Execute(w, struct {
+		LT      string
+		Captcha bool
+		Error   string
+	}{lt, s.CaptchaAnswer != "", errMsg})
+}
+
+// handleCaptcha 返回驗證碼圖片
+func (s *Server) handleCaptcha(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(captchaImage)
+}
@@ -0,0 +1,242 @@
+// Package fakeportal 在進程內模擬智慧山商門戶、CAS 登入與 WebVPN，
+// 用於離線運行 CourseTool 或在沒有校園帳號的環境中驗證登入、獲取與解析課表的完整流程。
+package fakeportal
+
+import (
+	"CourseTool/sdtbu"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 模擬環境中使用的校內地址，與真實門戶保持一致
+const (
+	CASLoginURL = "https://cas.sdtbu.edu.cn/cas/login" // 模擬的 CAS 登入地址
+	casHost     = "cas.sdtbu.edu.cn"
+	portalHost  = "zhss.sdtbu.edu.cn"
+	sessionName = "JSESSIONID" // 門戶會話 cookie 的名稱
+)
+
+// Server 是模擬的門戶服務器。修改導出字段後調用 Start 啟動，使用完畢後調用 Close。
+type Server struct {
+	Username string // 允許登入的學號
+	Password string // 對應的密碼
+
+	SchoolYear    string                   // 當前學年，例如 "2024-2025"
+	Term          string                   // 當前學期序號，例如 "2"
+	SemesterStart time.Time                // 第一教學週的星期一
+	Courses       []map[string]interface{} // 門戶格式的課程列表 (KCMC、SKXQ、SKJC、SKZC 等欄位)
+	Exams         []map[string]interface{} // 門戶格式的考試安排 (KCMC、KSRQ、KSSJ、KSDD、ZWH 等欄位)
+	Grades        []map[string]interface{} // 門戶格式的成績 (KCMC、XN、XQ、ZCJ、XF 等欄位)，運行中可用 PostGrade 追加
+
+	WebVPN        bool   // 為 true 時只接受經 WebVPN 改寫的地址，服務器本身充當 WebVPN 主機
+	CaptchaAnswer string // 非空時登入頁面要求輸入驗證碼，正確答案為該值
+	LockAfter     int    // 連續登入失敗達到該次數後返回帳號鎖定提示，0 表示不鎖定
+
+	URL string // 服務器的基礎地址，Start 後可用
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	nextID   int
+	lts      map[string]bool // 已發出且未使用的 lt
+	tickets  map[string]bool // 已發出且未兌換的 service ticket
+	sessions map[string]bool // 有效的門戶會話
+	failures int             // 連續登入失敗次數
+	logins   int             // 成功登入的次數
+}
+
+// New 創建使用示例帳號與課表的模擬門戶，當前日期位於學期的第 3 週
+func New() *Server {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return &Server{
+		Username:      "20230001",
+		Password:      "fake-password",
+		SchoolYear:    "2024-2025",
+		Term:          "2",
+		SemesterStart: monday.AddDate(0, 0, -14),
+		Courses:       DemoCourses(),
+		Exams:         DemoExams(today),
+		Grades:        DemoGrades(),
+	}
+}
+
+// DemoCourses 返回一份覆蓋週一至週五的示例課表，週四下午的物理課與實驗課單雙週交替
+func DemoCourses() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"KCMC": "高等數學", "JSXM": "張老師", "JXDD": "1號教學樓101", "SKXQ": "1", "SKJC": "1", "JSJC": "2", "SKZC": "1-16周"},
+		{"KCMC": "大學英語", "JSXM": "李老師", "JXDD": "2號教學樓203", "SKXQ": "2", "SKJC": "3", "JSJC": "4", "SKZC": "1-16周"},
+		{"KCMC": "程序設計基礎", "JSXM": "王老師", "JXDD": "實驗樓305", "SKXQ": "3", "SKJC": "5", "JSJC": "6", "SKZC": "1-8周"},
+		{"KCMC": "線性代數", "JSXM": "趙老師", "JXDD": "1號教學樓102", "SKXQ": "4", "SKJC": "1", "JSJC": "2", "SKZC": "1-16周"},
+		{"KCMC": "大學物理", "JSXM": "周老師", "JXDD": "1號教學樓105", "SKXQ": "4", "SKJC": "5", "JSJC": "6", "SKZC": "1-15周(单)"},
+		{"KCMC": "物理實驗", "JSXM": "周老師", "JXDD": "實驗樓210", "SKXQ": "4", "SKJC": "5", "JSJC": "6", "SKZC": "2-16周(双)"},
+		{"KCMC": "體育", "JSXM": "孫老師", "JXDD": "體育場", "SKXQ": "5", "SKJC": "7", "JSJC": "8", "SKZC": "2-16周"},
+	}
+}
+
+// DemoExams 返回兩場示例考試：明天上午與一週後下午
+func DemoExams(today time.Time) []map[string]interface{} {
+	return []map[string]interface{}{
+		{"KCMC": "大學英語", "KSRQ": today.AddDate(0, 0, 1).Format("2006-01-02"), "KSSJ": "09:00-11:00", "KSDD": "2號教學樓203", "ZWH": "15"},
+		{"KCMC": "高等數學", "KSRQ": today.AddDate(0, 0, 7).Format("2006-01-02"), "KSSJ": "14:00-16:00", "KSDD": "1號教學樓101", "ZWH": "8"},
+	}
+}
+
+// DemoGrades 返回上一學期已公佈的示例成績
+func DemoGrades() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"KCMC": "高等數學", "KCH": "MATH1001", "XN": "2024-2025", "XQ": "1", "ZCJ": "92", "XF": "5"},
+		{"KCMC": "大學英語", "KCH": "ENGL1001", "XN": "2024-2025", "XQ": "1", "ZCJ": "85", "XF": "4"},
+		{"KCMC": "思想道德與法治", "KCH": "POLI1001", "XN": "2024-2025", "XQ": "1", "ZCJ": "良好", "XF": "3"},
+		{"KCMC": "軍事理論", "KCH": "MILI1001", "XN": "2024-2025", "XQ": "1", "ZCJ": "合格", "XF": "2"},
+	}
+}
+
+// Start 啟動服務器
+func (s *Server) Start() {
+	s.lts = make(map[string]bool)
+	s.tickets = make(map[string]bool)
+	s.sessions = make(map[string]bool)
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+}
+
+// Close 關閉服務器
+func (s *Server) Close() {
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+// PortalResolver 返回客戶端連接到該服務器所需的地址解析器：
+// 普通模式使用 custom 模式的基礎地址，WebVPN 模式把服務器作為 WebVPN 主機。
+func (s *Server) PortalResolver() (*sdtbu.PortalResolver, error) {
+	if s.WebVPN {
+		return sdtbu.NewPortalResolver(sdtbu.PortalWebVPN, "", s.URL)
+	}
+	return sdtbu.NewPortalResolver(sdtbu.PortalCustom, s.URL+"/tp_up/", "")
+}
+
+// ExpireSessions 使所有門戶會話失效，模擬會話超時
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+// PostGrade 公佈一條新的成績，或替換同一學期同一課程代碼的已有成績，模擬教務處錄入成績
+func (s *Server) PostGrade(grade map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.Grades {
+		if existing["KCH"] == grade["KCH"] && existing["XN"] == grade["XN"] && existing["XQ"] == grade["XQ"] {
+			s.Grades[i] = grade
+			return
+		}
+	}
+	s.Grades = append(s.Grades, grade)
+}
+
+// LoginCount 返回成功登入的次數
+func (s *Server) LoginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// ServeHTTP 按校內地址分發請求。WebVPN 模式下先還原被改寫的主機名與路徑。
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, path := portalHost, r.URL.Path
+	if strings.HasPrefix(path, "/cas/") {
+		host = casHost
+	}
+	if s.WebVPN {
+		var ok bool
+		if host, path, ok = decodeWebVPNPath(r.URL.EscapedPath()); !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	switch {
+	case host == casHost && path == "/cas/login":
+		s.handleLogin(w, r)
+	case host == casHost && path == "/cas/captcha.jpg":
+		s.handleCaptcha(w, r)
+	case host == portalHost && strings.HasPrefix(path, "/tp_up/up/widgets/"):
+		s.handleWidget(w, r, strings.TrimPrefix(path, "/tp_up/up/widgets/"))
+	case host == portalHost && strings.HasPrefix(path, "/tp_up/"):
+		s.handlePage(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// external 把校內地址轉換為客戶端應訪問的地址
+func (s *Server) external(internal string) string {
+	if s.WebVPN {
+		rewritten, err := sdtbu.EncodeWebVPNURL(s.URL, internal, "")
+		if err != nil {
+			panic(err) // 校內地址均為常量拼接，不會失敗
+		}
+		return rewritten
+	}
+	parsed, _ := url.Parse(internal)
+	return s.URL + parsed.RequestURI()
+}
+
+// newID 生成遞增的標識，用於 lt、ticket 與會話
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d-fake", prefix, s.nextID)
+}
+
+// hasSession 判斷請求是否攜帶有效的門戶會話
+func (s *Server) hasSession(r *http.Request) bool {
+	cookie, err := r.Cookie(sessionName)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[cookie.Value]
+}
+
+// redirectToLogin 將未登入的請求重定向到 CAS
+func (s *Server) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	target := CASLoginURL + "?service=" + url.QueryEscape(sdtbu.DefaultPortalURL)
+	http.Redirect(w, r, s.external(target), http.StatusFound)
+}
+
+// handlePage 處理門戶頁面：兌換 CAS 發出的 ticket，或在未登入時重定向到 CAS
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		s.mu.Lock()
+		valid := s.tickets[ticket]
+		delete(s.tickets, ticket)
+		session := s.newID("SESSION")
+		if valid {
+			s.sessions[session] = true
+		}
+		s.mu.Unlock()
+		if !valid {
+			s.redirectToLogin(w, r)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionName, Value: session, Path: "/", HttpOnly: true})
+		http.Redirect(w, r, s.external(sdtbu.DefaultPortalURL+"view?m=up"), http.StatusFound)
+		return
+	}
+	if !s.hasSession(r) {
+		s.redirectToLogin(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<html><head><title>智慧山商</title></head><body>歡迎，%s</body></html>", template.HTMLEscapeString(s.Username))
+}
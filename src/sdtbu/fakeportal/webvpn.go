@@ -0,0 +1,53 @@
+package fakeportal
+
+import (
+	"CourseTool/sdtbu"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// decodeWebVPNPath 還原 WebVPN 改寫的路徑 /<協議>[-<端口>]/<hex(IV)+hex(加密主機名)>/<路徑>，
+// 返回原始主機名與路徑
+func decodeWebVPNPath(escapedPath string) (host, path string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(escapedPath, "/"), "/", 3)
+	if len(parts) < 2 || (parts[0] != "https" && !strings.HasPrefix(parts[0], "https-") &&
+		parts[0] != "http" && !strings.HasPrefix(parts[0], "http-")) {
+		return "", "", false
+	}
+	host, ok = decryptWebVPNHost(parts[1])
+	if !ok {
+		return "", "", false
+	}
+	path = "/"
+	if len(parts) == 3 {
+		unescaped, err := url.PathUnescape(parts[2])
+		if err != nil {
+			return "", "", false
+		}
+		path += unescaped
+	}
+	return host, path, true
+}
+
+// decryptWebVPNHost 解密 hex(IV) + hex(AES-128-CFB 密文) 形式的主機名
+func decryptWebVPNHost(encoded string) (string, bool) {
+	key := sdtbu.DefaultWebVPNKey
+	ivHex := hex.EncodeToString([]byte(key))
+	if !strings.HasPrefix(encoded, ivHex) {
+		return "", false
+	}
+	encrypted, err := hex.DecodeString(strings.TrimPrefix(encoded, ivHex))
+	if err != nil {
+		return "", false
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", false
+	}
+	host := make([]byte, len(encrypted))
+	cipher.NewCFBDecrypter(block, []byte(key)).XORKeyStream(host, encrypted)
+	return string(host), true
+}
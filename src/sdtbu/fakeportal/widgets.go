@@ -0,0 +1,104 @@
+package fakeportal
+
+import (
+	"CourseTool/sdtbu"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleWidget 處理門戶的 widgets 接口。未登入時與真實門戶一樣重定向到 CAS。
+func (s *Server) handleWidget(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.hasSession(r) {
+		s.redirectToLogin(w, r)
+		return
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch name {
+	case "getClassbyUserInfo":
+		if !s.matchesSemester(body) {
+			writeJSON(w, []interface{}{})
+			return
+		}
+		writeJSON(w, s.Courses)
+	case "getClassbyTime":
+		week, err := strconv.Atoi(stringField(body, "learnWeek"))
+		if err != nil || !s.matchesSemester(body) {
+			http.Error(w, "invalid learnWeek or semester", http.StatusBadRequest)
+			return
+		}
+		if _, ok := body["classList"].([]interface{}); !ok {
+			http.Error(w, "missing classList", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, s.coursesInWeek(week))
+	case sdtbu.ExamWidget:
+		if !s.matchesSemester(body) {
+			writeJSON(w, []interface{}{})
+			return
+		}
+		writeJSON(w, s.Exams)
+	case sdtbu.GradeWidget:
+		s.mu.Lock()
+		grades := append([]map[string]interface{}{}, s.Grades...)
+		s.mu.Unlock()
+		writeJSON(w, grades)
+	case "getLearnweekbyDate":
+		date, err := time.ParseInLocation("2006-01-02", stringField(body, "schoolDate"), time.Local)
+		if err != nil {
+			http.Error(w, "invalid schoolDate", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"schoolYear": s.SchoolYear,
+			"semester":   s.Term,
+			"learnWeek":  int(date.Sub(s.SemesterStart).Hours()/24)/7 + 1,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// matchesSemester 判斷請求的學年與學期是否為當前學期
+func (s *Server) matchesSemester(body map[string]interface{}) bool {
+	return stringField(body, "schoolYear") == s.SchoolYear && stringField(body, "semester") == s.Term
+}
+
+// coursesInWeek 返回在指定教學週上課的課程，沒有週次資訊的課程每週都返回
+func (s *Server) coursesInWeek(week int) []map[string]interface{} {
+	courses := []map[string]interface{}{}
+	for _, course := range s.Courses {
+		spec, _ := course["SKZC"].(string)
+		ranges, err := sdtbu.ParseWeekSpec(spec)
+		if err != nil {
+			continue
+		}
+		if parsed := (sdtbu.Course{Weeks: ranges}); parsed.MeetsInWeek(week) {
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
+
+// stringField 讀取請求體中的字串欄位
+func stringField(body map[string]interface{}, key string) string {
+	value, _ := body[key].(string)
+	return value
+}
+
+// writeJSON 以門戶的格式返回 JSON 響應
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
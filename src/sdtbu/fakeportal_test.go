@@ -0,0 +1,393 @@
+package sdtbu_test
+
+import (
+	"CourseTool/clock"
+	"CourseTool/sdtbu"
+	"CourseTool/sdtbu/fakeportal"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// semesterStart 是測試中第一教學週的星期一，2025-03-13 (星期四) 位於第 3 週
+var semesterStart = time.Date(2025, 2, 24, 0, 0, 0, 0, time.Local)
+
+// startPortal 啟動使用示例課表的模擬門戶，configure 可在啟動前修改設定
+func startPortal(t *testing.T, configure func(*fakeportal.Server)) *fakeportal.Server {
+	t.Helper()
+	server := fakeportal.New()
+	server.SemesterStart = semesterStart
+	if configure != nil {
+		configure(server)
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newSession 創建連接到 server 的會話，不保存會話文件
+func newSession(t *testing.T, server *fakeportal.Server) *sdtbu.ClientSession {
+	t.Helper()
+	session, err := sdtbu.NewClientSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Resolver, err = server.PortalResolver(); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// setClock 將 sdtbu 的時鐘固定在 at，測試結束後恢復系統時鐘
+func setClock(t *testing.T, at time.Time) *clock.Manual {
+	t.Helper()
+	manual := clock.NewManual(at)
+	sdtbu.SetClock(manual)
+	t.Cleanup(func() { sdtbu.SetClock(nil) })
+	return manual
+}
+
+func TestLoginDirect(t *testing.T) {
+	server := startPortal(t, nil)
+	session := newSession(t, server)
+	ctx := context.Background()
+
+	if err := session.Login(ctx, server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if got := server.LoginCount(); got != 1 {
+		t.Errorf("LoginCount = %d, want 1", got)
+	}
+	if err := session.GetClassbyUserInfo(ctx); err != nil {
+		t.Fatalf("GetClassbyUserInfo: %v", err)
+	}
+}
+
+func TestLoginWebVPN(t *testing.T) {
+	setClock(t, time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local))
+	server := startPortal(t, func(s *fakeportal.Server) { s.WebVPN = true })
+	session := newSession(t, server)
+	ctx := context.Background()
+
+	if err := session.Login(ctx, server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	semester, err := session.ResolveSemester(ctx, time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("ResolveSemester: %v", err)
+	}
+	if !semester.StartDate.Equal(semesterStart) {
+		t.Errorf("StartDate = %v, want %v", semester.StartDate, semesterStart)
+	}
+	if err := session.GetClassbyUserInfo(ctx); err != nil {
+		t.Fatalf("GetClassbyUserInfo: %v", err)
+	}
+	courses, err := session.FetchWeek(ctx, semester, 3)
+	if err != nil {
+		t.Fatalf("FetchWeek: %v", err)
+	}
+	if len(courses) == 0 {
+		t.Error("經 WebVPN 獲取的第 3 週課表為空")
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("bad credentials", func(t *testing.T) {
+		server := startPortal(t, nil)
+		err := newSession(t, server).Login(ctx, server.Username, "wrong-password")
+		if !errors.Is(err, sdtbu.ErrBadCredentials) {
+			t.Errorf("Login error = %v, want ErrBadCredentials", err)
+		}
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		server := startPortal(t, func(s *fakeportal.Server) { s.LockAfter = 2 })
+		for i := 0; i < 2; i++ {
+			if err := newSession(t, server).Login(ctx, server.Username, "wrong-password"); !errors.Is(err, sdtbu.ErrBadCredentials) {
+				t.Fatalf("第 %d 次登入錯誤 = %v, want ErrBadCredentials", i+1, err)
+			}
+		}
+		err := newSession(t, server).Login(ctx, server.Username, server.Password)
+		if !errors.Is(err, sdtbu.ErrAccountLocked) {
+			t.Errorf("Login error = %v, want ErrAccountLocked", err)
+		}
+	})
+
+	t.Run("captcha without solver", func(t *testing.T) {
+		server := startPortal(t, func(s *fakeportal.Server) { s.CaptchaAnswer = "4kx9" })
+		err := newSession(t, server).Login(ctx, server.Username, server.Password)
+		if !errors.Is(err, sdtbu.ErrCaptchaRequired) {
+			t.Errorf("Login error = %v, want ErrCaptchaRequired", err)
+		}
+	})
+
+	t.Run("captcha wrong answer", func(t *testing.T) {
+		server := startPortal(t, func(s *fakeportal.Server) { s.CaptchaAnswer = "4kx9" })
+		session := newSession(t, server)
+		attempts := 0
+		session.CaptchaSolver = sdtbu.CaptchaSolverFunc(func(ctx context.Context, challenge *sdtbu.CaptchaChallenge) (string, error) {
+			attempts++
+			return "0000", nil
+		})
+		err := session.Login(ctx, server.Username, server.Password)
+		if !errors.Is(err, sdtbu.ErrCaptchaRequired) {
+			t.Errorf("Login error = %v, want ErrCaptchaRequired", err)
+		}
+		if attempts != sdtbu.MaxCaptchaAttempts {
+			t.Errorf("識別了 %d 次驗證碼，want %d", attempts, sdtbu.MaxCaptchaAttempts)
+		}
+	})
+
+	t.Run("captcha solved", func(t *testing.T) {
+		server := startPortal(t, func(s *fakeportal.Server) { s.CaptchaAnswer = "4kx9" })
+		session := newSession(t, server)
+		session.CaptchaSolver = sdtbu.CaptchaSolverFunc(func(ctx context.Context, challenge *sdtbu.CaptchaChallenge) (string, error) {
+			if len(challenge.Image) == 0 {
+				t.Error("驗證碼圖片為空")
+			}
+			return "4kx9", nil
+		})
+		if err := session.Login(ctx, server.Username, server.Password); err != nil {
+			t.Errorf("Login: %v", err)
+		}
+	})
+}
+
+func TestFetchSemesterNextOccurrence(t *testing.T) {
+	thursday := time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local) // 第 3 週 (單週) 星期四
+	manual := setClock(t, thursday)
+	server := startPortal(t, nil)
+	session := newSession(t, server)
+	ctx := context.Background()
+
+	if err := session.Login(ctx, server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	timetable, err := session.FetchSemester(ctx, 2)
+	if err != nil {
+		t.Fatalf("FetchSemester: %v", err)
+	}
+	if got, want := len(timetable.Weeks), timetable.Semester.Weeks; got != want {
+		t.Fatalf("獲取了 %d 週課表，want %d", got, want)
+	}
+	if got := timetable.Semester.WeekOf(thursday); got != 3 {
+		t.Fatalf("WeekOf(%s) = %d, want 3", thursday.Format("2006-01-02"), got)
+	}
+	if len(timetable.Exams) != 0 {
+		t.Errorf("FetchSemester 獲取了 %d 場考試，考試安排應單獨獲取", len(timetable.Exams))
+	}
+
+	tests := []struct {
+		now        time.Time
+		wantCourse string
+		wantDate   time.Time
+		wantWeek   int
+		wantRemark string
+	}{
+		// 早上的線性代數已經結束，單週下午上大學物理
+		{thursday, "大學物理", time.Date(2025, 3, 13, 0, 0, 0, 0, time.Local), 3, ""},
+		// 雙週同一時段改上物理實驗
+		{thursday.AddDate(0, 0, 7), "物理實驗", time.Date(2025, 3, 20, 0, 0, 0, 0, time.Local), 4, ""},
+		// 星期四晚上：明天上午沒有課，下午上體育
+		{time.Date(2025, 3, 13, 21, 0, 0, 0, time.Local), "體育", time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local), 3, "明天的首節課程"},
+		// 星期五晚上跨過週末到下週一
+		{time.Date(2025, 3, 14, 21, 0, 0, 0, time.Local), "高等數學", time.Date(2025, 3, 17, 0, 0, 0, 0, time.Local), 4, "03-17"},
+	}
+	for _, tt := range tests {
+		occurrence, err := timetable.NextOccurrence(ctx, tt.now, sdtbu.DefaultLookaheadDays, nil)
+		if err != nil {
+			t.Errorf("NextOccurrence(%s): %v", tt.now.Format("2006-01-02 15:04"), err)
+			continue
+		}
+		if occurrence.Course.Name != tt.wantCourse || !occurrence.Date.Equal(tt.wantDate) || occurrence.Week != tt.wantWeek {
+			t.Errorf("NextOccurrence(%s) = %s on %s (week %d), want %s on %s (week %d)",
+				tt.now.Format("2006-01-02 15:04"), occurrence.Course.Name, occurrence.Date.Format("2006-01-02"), occurrence.Week,
+				tt.wantCourse, tt.wantDate.Format("2006-01-02"), tt.wantWeek)
+		}
+		if !strings.Contains(occurrence.Course.Remark, tt.wantRemark) {
+			t.Errorf("NextOccurrence(%s).Remark = %q, want containing %q", tt.now.Format("2006-01-02 15:04"), occurrence.Course.Remark, tt.wantRemark)
+		}
+	}
+
+	// NextClass 使用 sdtbu 的時鐘：撥到星期五晚上後，下一節課是下週一的高等數學
+	manual.Set(time.Date(2025, 3, 14, 21, 0, 0, 0, time.Local))
+	course, err := timetable.NextClass()
+	if err != nil {
+		t.Fatalf("NextClass: %v", err)
+	}
+	if course.Name != "高等數學" {
+		t.Errorf("NextClass = %s, want 高等數學", course.Name)
+	}
+}
+
+func TestReloginAfterSessionExpired(t *testing.T) {
+	now := time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local)
+	setClock(t, now)
+	server := startPortal(t, nil)
+	session := newSession(t, server)
+	ctx := context.Background()
+
+	if err := session.Login(ctx, server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	semester, err := session.ResolveSemester(ctx, now)
+	if err != nil {
+		t.Fatalf("ResolveSemester: %v", err)
+	}
+	if err := session.GetClassbyUserInfo(ctx); err != nil {
+		t.Fatalf("GetClassbyUserInfo: %v", err)
+	}
+
+	server.ExpireSessions()
+	courses, err := session.FetchWeek(ctx, semester, 3)
+	if err != nil {
+		t.Fatalf("會話失效後 FetchWeek: %v", err)
+	}
+	if len(courses) == 0 {
+		t.Error("重新登入後獲取的課表為空")
+	}
+	if got := server.LoginCount(); got != 2 {
+		t.Errorf("LoginCount = %d, want 2 (自動重新登入一次)", got)
+	}
+}
+
+// TestConcurrentAccounts 模擬多帳號：每個帳號有獨立的會話，排程器推送、控制台命令與成績輪詢
+// 會在各自的 goroutine 中同時使用同一個會話。需要配合 go test -race 運行。
+func TestConcurrentAccounts(t *testing.T) {
+	now := time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local)
+	setClock(t, now)
+	ctx := context.Background()
+
+	var sessions []*sdtbu.ClientSession
+	for i := 0; i < 2; i++ {
+		server := startPortal(t, nil)
+		session := newSession(t, server)
+		if err := session.Login(ctx, server.Username, server.Password); err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	errs := make(chan error, len(sessions)*3)
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := session.FetchSemester(ctx, 2)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := session.ResolveSemester(ctx, now)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := session.NextClass([]sdtbu.Course{{Name: "高等數學", Weekday: 4, StartLesson: 9, EndLesson: 10}})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFetchExamsStore(t *testing.T) {
+	manual := setClock(t, time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local))
+	server := startPortal(t, nil)
+	session := newSession(t, server)
+	ctx := context.Background()
+
+	if err := session.Login(ctx, server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	semester, err := session.ResolveSemester(ctx, manual.Now())
+	if err != nil {
+		t.Fatalf("ResolveSemester: %v", err)
+	}
+	exams, err := session.FetchExams(ctx, semester)
+	if err != nil {
+		t.Fatalf("FetchExams: %v", err)
+	}
+	if len(exams) != len(server.Exams) || len(exams) == 0 {
+		t.Fatalf("獲取了 %d 場考試，want %d", len(exams), len(server.Exams))
+	}
+
+	// 已發送的提醒與考試安排一起保存，重新載入後仍然存在
+	store := &sdtbu.ExamStore{Dir: t.TempDir()}
+	if _, err := store.Load("alice"); !errors.Is(err, sdtbu.ErrCacheMiss) {
+		t.Fatalf("Load 空目錄 error = %v, want ErrCacheMiss", err)
+	}
+	sent := exams[0].Key() + "|24h0m0s"
+	if err := store.Save("alice", &sdtbu.ExamSnapshot{Exams: exams, FetchedAt: manual.Now(), SentReminders: []string{sent}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	snapshot, err := store.Load("alice")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(snapshot.SentReminders) != 1 || snapshot.SentReminders[0] != sent {
+		t.Errorf("SentReminders = %v, want [%s]", snapshot.SentReminders, sent)
+	}
+	if next := snapshot.Next(manual.Now()); next == nil || next.Key() != exams[0].Key() {
+		t.Errorf("Next = %v, want %s", next, exams[0].Key())
+	}
+	if upcoming := snapshot.Upcoming(exams[len(exams)-1].End); len(upcoming) != 0 {
+		t.Errorf("最後一場考試結束後 Upcoming = %v", upcoming)
+	}
+}
+
+// roundTripFunc 將函數用作 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestFetchSemesterCancelled(t *testing.T) {
+	setClock(t, time.Date(2025, 3, 13, 10, 0, 0, 0, time.Local))
+	server := startPortal(t, nil)
+	session := newSession(t, server)
+	if err := session.Login(context.Background(), server.Username, server.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// 第一週的響應成功返回後取消，之後的週次不再派發
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	base := session.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var once sync.Once
+	session.Client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if err != nil || !strings.Contains(req.URL.Path, "getClassbyTime") {
+			return resp, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		once.Do(cancel)
+		return resp, nil
+	})
+
+	timetable, err := session.FetchSemester(ctx, 1)
+	if err == nil {
+		t.Fatalf("取消後 FetchSemester 返回了 %d/%d 週的課表而沒有錯誤", len(timetable.Weeks), timetable.Semester.Weeks)
+	}
+}
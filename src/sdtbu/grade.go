@@ -0,0 +1,256 @@
+package sdtbu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GradeWidget 是門戶中查詢成績的 widgets 接口名稱
+var GradeWidget = "getStudentScore"
+
+// Grade 表示一門課程的成績
+type Grade struct {
+	Course     string                 `json:"course"`               // 課程名稱 (KCMC)
+	CourseCode string                 `json:"courseCode,omitempty"` // 課程代碼 (KCH)
+	SchoolYear string                 `json:"schoolYear,omitempty"` // 學年，例如 "2024-2025"
+	Term       string                 `json:"term,omitempty"`       // 學期序號，例如 "1"
+	Score      string                 `json:"score"`                // 成績，可能是分數或 "優秀"、"合格" 等等級
+	Credit     float64                `json:"credit"`               // 學分 (XF)
+	GradePoint *float64               `json:"gradePoint,omitempty"` // 門戶給出的績點 (JD)，為 nil 時按成績換算
+	Raw        map[string]interface{} `json:"raw,omitempty"`        // 門戶返回的原始數據
+}
+
+// 成績各欄位在門戶中可能使用的鍵名，按優先順序排列
+var (
+	gradeCourseKeys     = []string{"KCMC", "KCM", "courseName"}
+	gradeCodeKeys       = []string{"KCH", "KCDM", "courseCode"}
+	gradeYearKeys       = []string{"XN", "XNMC", "schoolYear"}
+	gradeTermKeys       = []string{"XQ", "XQM", "semester"}
+	gradeScoreKeys      = []string{"ZCJ", "CJ", "ZPCJ", "score"}
+	gradeCreditKeys     = []string{"XF", "credit"}
+	gradeGradePointKeys = []string{"JD", "gradePoint"}
+)
+
+// 等級制成績換算為百分制時使用的分數，"合格"、"通過" 等兩級制成績不計入 GPA
+var gradeLevelScores = map[string]float64{
+	"優秀": 95, "优秀": 95,
+	"良好":  85,
+	"中等":  75,
+	"及格":  65,
+	"不及格": 0,
+}
+
+// Key 返回成績的唯一標識：同一學期的同一門課程
+func (g *Grade) Key() string {
+	course := g.CourseCode
+	if course == "" {
+		course = g.Course
+	}
+	return g.SchoolYear + "-" + g.Term + "|" + course
+}
+
+// TermLabel 返回成績所屬學期的顯示文字，例如 "2024-2025 學年第 1 學期"
+func (g *Grade) TermLabel() string {
+	if g.SchoolYear == "" {
+		return "未知學期"
+	}
+	return fmt.Sprintf("%s 學年第 %s 學期", g.SchoolYear, g.Term)
+}
+
+// String 返回成績的簡短描述，例如 "95 (3 學分)"
+func (g *Grade) String() string {
+	score := g.Score
+	if score == "" {
+		score = "未錄入"
+	}
+	return fmt.Sprintf("%s (%s 學分)", score, strings.TrimSuffix(strconv.FormatFloat(g.Credit, 'f', 1, 64), ".0"))
+}
+
+// Points 返回該成績的績點。門戶沒有給出績點時按 (分數 - 50) / 10 換算，
+// 不及格為 0；無法換算 (例如 "合格") 時 ok 為 false，該課程不計入 GPA。
+func (g *Grade) Points() (points float64, ok bool) {
+	if g.GradePoint != nil {
+		return *g.GradePoint, true
+	}
+	score, err := strconv.ParseFloat(g.Score, 64)
+	if err != nil {
+		if score, ok = gradeLevelScores[g.Score]; !ok {
+			return 0, false
+		}
+	}
+	if score < 60 {
+		return 0, true
+	}
+	return (score - 50) / 10, true
+}
+
+// ParseGrade 將門戶返回的單個成績記錄轉換為 Grade
+func ParseGrade(raw map[string]interface{}) (Grade, error) {
+	grade := Grade{
+		Course:     lookupString(raw, gradeCourseKeys),
+		CourseCode: lookupString(raw, gradeCodeKeys),
+		SchoolYear: lookupString(raw, gradeYearKeys),
+		Term:       lookupString(raw, gradeTermKeys),
+		Score:      lookupString(raw, gradeScoreKeys),
+		Raw:        raw,
+	}
+	if grade.Course == "" {
+		return Grade{}, fmt.Errorf("成績記錄缺少課程名稱: %v", raw)
+	}
+	if credit, err := strconv.ParseFloat(lookupString(raw, gradeCreditKeys), 64); err == nil {
+		grade.Credit = credit
+	}
+	if points, err := strconv.ParseFloat(lookupString(raw, gradeGradePointKeys), 64); err == nil {
+		grade.GradePoint = &points
+	}
+	return grade, nil
+}
+
+// GPA 返回按學分加權的平均績點。無法換算績點或沒有學分的課程不計入；
+// 沒有可計入的課程時 ok 為 false。
+func GPA(grades []Grade) (gpa float64, ok bool) {
+	var totalPoints, totalCredits float64
+	for i := range grades {
+		points, ok := grades[i].Points()
+		if !ok || grades[i].Credit <= 0 {
+			continue
+		}
+		totalPoints += points * grades[i].Credit
+		totalCredits += grades[i].Credit
+	}
+	if totalCredits == 0 {
+		return 0, false
+	}
+	return totalPoints / totalCredits, true
+}
+
+// GradeChange 表示一條新出現或發生變化的成績
+type GradeChange struct {
+	Grade    Grade  // 最新的成績
+	Previous *Grade // 變化前的成績，新出現的成績為 nil
+}
+
+// DiffGrades 比較兩次獲取的成績，返回新出現或分數、學分、績點發生變化的成績，按原有順序排列
+func DiffGrades(previous, current []Grade) []GradeChange {
+	known := make(map[string]Grade, len(previous))
+	for _, grade := range previous {
+		known[grade.Key()] = grade
+	}
+	var changes []GradeChange
+	for _, grade := range current {
+		old, ok := known[grade.Key()]
+		switch {
+		case !ok:
+			changes = append(changes, GradeChange{Grade: grade})
+		case !sameGrade(&old, &grade):
+			changes = append(changes, GradeChange{Grade: grade, Previous: &old})
+		}
+	}
+	return changes
+}
+
+// sameGrade 判斷兩條成績記錄的分數、學分與績點是否相同
+func sameGrade(a, b *Grade) bool {
+	if a.Score != b.Score || a.Credit != b.Credit {
+		return false
+	}
+	if (a.GradePoint == nil) != (b.GradePoint == nil) {
+		return false
+	}
+	return a.GradePoint == nil || *a.GradePoint == *b.GradePoint
+}
+
+// FetchGrades 獲取所有學期的成績，按學年、學期與課程名稱排序。
+// 會話失效時自動重新登入後重試一次。
+func (cs *ClientSession) FetchGrades(ctx context.Context) ([]Grade, error) {
+	var bodyBytes []byte
+	err := cs.withRelogin(ctx, func() error {
+		var err error
+		// 學年與學期留空表示查詢全部學期
+		bodyBytes, err = cs.postWidget(ctx, GradeWidget, map[string]string{
+			"schoolYear": "",
+			"semester":   "",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rawGrades []map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &rawGrades); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s response: %w", GradeWidget, err)
+	}
+
+	grades := make([]Grade, 0, len(rawGrades))
+	for _, raw := range rawGrades {
+		grade, err := ParseGrade(raw)
+		if err != nil {
+			cs.log().Warnf("跳過無效的成績記錄: %v", err)
+			continue
+		}
+		grades = append(grades, grade)
+	}
+	sort.SliceStable(grades, func(i, j int) bool {
+		if grades[i].SchoolYear != grades[j].SchoolYear {
+			return grades[i].SchoolYear < grades[j].SchoolYear
+		}
+		if grades[i].Term != grades[j].Term {
+			return grades[i].Term < grades[j].Term
+		}
+		return grades[i].Course < grades[j].Course
+	})
+	return grades, nil
+}
+
+// GradeSnapshot 是某次獲取的全部成績，用於與下一次獲取的結果比較
+type GradeSnapshot struct {
+	Grades    []Grade   `json:"grades"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// GradeStore 將最近一次看到的成績以 JSON 文件的形式保存在本地磁碟上
+type GradeStore struct {
+	Dir string // 保存目錄，通常與課表緩存相同
+}
+
+// Load 讀取保存的成績快照，文件不存在時返回 ErrCacheMiss
+func (s *GradeStore) Load(key string) (*GradeSnapshot, error) {
+	data, err := os.ReadFile(cachePath(s.Dir, "grades", key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取成績快照失敗: %w", err)
+	}
+
+	var snapshot GradeSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析成績快照失敗: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save 寫入成績快照。先寫入臨時文件再重命名，避免中途中斷留下損壞的快照。
+func (s *GradeStore) Save(key string, snapshot *GradeSnapshot) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("創建緩存目錄失敗: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化成績失敗: %w", err)
+	}
+
+	if err := writeFileAtomic(cachePath(s.Dir, "grades", key), data); err != nil {
+		return fmt.Errorf("寫入成績快照失敗: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package sdtbu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// HolidayCalendar 描述法定假期與調休安排，通常每年一個文件
+type HolidayCalendar struct {
+	Holidays   []Holiday   `json:"holidays"`   // 停課的假期
+	MakeupDays []MakeupDay `json:"makeupDays"` // 調休上課的日期
+}
+
+// Holiday 是一段停課的日期區間
+type Holiday struct {
+	Name string `json:"name"` // 假期名稱，例如 "勞動節"
+	From string `json:"from"` // 開始日期 "YYYY-MM-DD"
+	To   string `json:"to"`   // 結束日期 "YYYY-MM-DD" (包含)，為空表示只有一天
+}
+
+// MakeupDay 是調休上課的日期，當天按另一天的課表上課。
+// 設定 FollowsDate 時按該日期 (所在教學週與星期) 的課表上課；
+// 否則按同一教學週中 Weekday 的課表上課。
+type MakeupDay struct {
+	Name        string `json:"name"`                  // 說明，例如 "勞動節調休"
+	Date        string `json:"date"`                  // 調休上課的日期 "YYYY-MM-DD"
+	Weekday     int    `json:"weekday,omitempty"`     // 按星期幾的課表上課 (1=星期一 ... 7=星期日)
+	FollowsDate string `json:"followsDate,omitempty"` // 按哪一天的課表上課 "YYYY-MM-DD"，優先於 Weekday
+}
+
+// DaySchedule 描述某一天實際的上課安排
+type DaySchedule struct {
+	Date    time.Time // 查詢的日期 (查詢時間所在時區的零點)
+	NoClass bool      // 當天是否因假期停課
+	Follows time.Time // 當天按哪一天的課表上課，普通日期與 Date 相同
+	Reason  string    // 假期或調休的名稱，普通日期為空
+}
+
+// IsMakeup 判斷當天是否為按其他日期課表上課的調休日
+func (d *DaySchedule) IsMakeup() bool {
+	return !d.NoClass && !d.Follows.Equal(d.Date)
+}
+
+// Weekday 返回當天實際執行的上課星期 (SKXQ，1=星期一 ... 7=星期日)
+func (d *DaySchedule) Weekday() int {
+	return goWeekdayToApiSkxq(d.Follows.Weekday())
+}
+
+var (
+	holidayCalendarMu sync.RWMutex
+	holidayCalendar   = &HolidayCalendar{}
+)
+
+// LoadHolidayCalendar 從 JSON 文件中讀取假期與調休安排並校驗其中的日期
+func LoadHolidayCalendar(path string) (*HolidayCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取假期文件 %s 失敗: %w", path, err)
+	}
+
+	var calendar HolidayCalendar
+	if err := json.Unmarshal(data, &calendar); err != nil {
+		return nil, fmt.Errorf("解析假期文件 %s 失敗: %w", path, err)
+	}
+
+	for _, holiday := range calendar.Holidays {
+		from, errFrom := parseCalendarDate(holiday.From, time.Local)
+		to, errTo := from, error(nil)
+		if holiday.To != "" {
+			to, errTo = parseCalendarDate(holiday.To, time.Local)
+		}
+		if errFrom != nil || errTo != nil || to.Before(from) {
+			return nil, fmt.Errorf("假期 '%s' 的日期 %s 至 %s 無效，預期格式為 YYYY-MM-DD", holiday.Name, holiday.From, holiday.To)
+		}
+	}
+	for _, makeup := range calendar.MakeupDays {
+		_, errDate := parseCalendarDate(makeup.Date, time.Local)
+		errFollows := error(nil)
+		if makeup.FollowsDate != "" {
+			_, errFollows = parseCalendarDate(makeup.FollowsDate, time.Local)
+		} else if makeup.Weekday < 1 || makeup.Weekday > 7 {
+			errFollows = fmt.Errorf("必須設定 weekday (1-7) 或 followsDate")
+		}
+		if errDate != nil || errFollows != nil {
+			return nil, fmt.Errorf("調休 '%s' (%s) 無效: %w", makeup.Name, makeup.Date, firstError(errDate, errFollows))
+		}
+	}
+
+	return &calendar, nil
+}
+
+// SetHolidayCalendar 設定全局使用的假期與調休安排，傳入 nil 時清除 (每天都按星期上課)
+func SetHolidayCalendar(calendar *HolidayCalendar) {
+	if calendar == nil {
+		calendar = &HolidayCalendar{}
+	}
+	holidayCalendarMu.Lock()
+	holidayCalendar = calendar
+	holidayCalendarMu.Unlock()
+}
+
+// ScheduleOn 返回指定日期實際的上課安排。調休優先於假期，
+// 因此調休上課的日期即使落在假期區間內也照常上課。
+func ScheduleOn(date time.Time) DaySchedule {
+	// 使用 date 自身的時區，與 NextOccurrence、CoursesOn 的調用方 (例如 -now 或注入的時鐘) 保持一致
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	schedule := DaySchedule{Date: day, Follows: day}
+	key := day.Format("2006-01-02")
+
+	holidayCalendarMu.RLock()
+	defer holidayCalendarMu.RUnlock()
+
+	for _, makeup := range holidayCalendar.MakeupDays {
+		if makeup.Date != key {
+			continue
+		}
+		if follows, err := parseCalendarDate(makeup.FollowsDate, day.Location()); err == nil {
+			schedule.Follows = follows
+		} else {
+			schedule.Follows = mondayOf(day).AddDate(0, 0, makeup.Weekday-1)
+		}
+		schedule.Reason = makeup.Name
+		return schedule
+	}
+	for _, holiday := range holidayCalendar.Holidays {
+		to := holiday.To
+		if to == "" {
+			to = holiday.From
+		}
+		if key >= holiday.From && key <= to {
+			schedule.NoClass = true
+			schedule.Reason = holiday.Name
+			return schedule
+		}
+	}
+	return schedule
+}
+
+// parseCalendarDate 解析 "YYYY-MM-DD" 格式的日期 (loc 時區零點)
+func parseCalendarDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// firstError 返回第一個非 nil 的錯誤
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package sdtbu_test
+
+import (
+	"CourseTool/sdtbu"
+	"context"
+	"testing"
+	"time"
+)
+
+// setHolidays 設定假期與調休安排，測試結束後清除
+func setHolidays(t *testing.T, calendar *sdtbu.HolidayCalendar) {
+	t.Helper()
+	sdtbu.SetHolidayCalendar(calendar)
+	t.Cleanup(func() { sdtbu.SetHolidayCalendar(nil) })
+}
+
+func TestNextOccurrenceMakeupDayWeek(t *testing.T) {
+	// 第 4 週星期六調休，按第 3 週星期五 (03-14) 的課表上課
+	setHolidays(t, &sdtbu.HolidayCalendar{MakeupDays: []sdtbu.MakeupDay{{Name: "調休", Date: "2025-03-22", FollowsDate: "2025-03-14"}}})
+	timetable := &sdtbu.Timetable{
+		Semester: sdtbu.Semester{SchoolYear: "2024-2025", Term: "2", StartDate: semesterStart, Weeks: 16},
+		Weeks: map[int][]sdtbu.Course{
+			3: {{Name: "體育", Weekday: 5, StartLesson: 3, EndLesson: 4, Weeks: []sdtbu.WeekRange{{Start: 3, End: 3}}}},
+			4: {},
+		},
+	}
+
+	occurrence, err := timetable.NextOccurrence(context.Background(), time.Date(2025, 3, 22, 7, 0, 0, 0, time.Local), 0, nil)
+	if err != nil {
+		t.Fatalf("NextOccurrence: %v", err)
+	}
+	if occurrence.Course.Name != "體育" || occurrence.Week != 3 {
+		t.Errorf("調休日的下一節課 = %s (第 %d 週)，want 體育 (第 3 週)", occurrence.Course.Name, occurrence.Week)
+	}
+}
+
+func TestScheduleOnKeepsLocation(t *testing.T) {
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	setHolidays(t, &sdtbu.HolidayCalendar{
+		Holidays:   []sdtbu.Holiday{{Name: "清明節", From: "2025-04-04"}},
+		MakeupDays: []sdtbu.MakeupDay{{Name: "調休", Date: "2025-03-22", FollowsDate: "2025-03-14"}},
+	})
+
+	// 時區零點附近的時間按該時區的日期查找假期與調休
+	holiday := sdtbu.ScheduleOn(time.Date(2025, 4, 4, 0, 30, 0, 0, tokyo))
+	if !holiday.NoClass || !holiday.Date.Equal(time.Date(2025, 4, 4, 0, 0, 0, 0, tokyo)) {
+		t.Errorf("ScheduleOn(04-04 00:30 UTC+9) = %+v, want 清明節停課", holiday)
+	}
+	makeup := sdtbu.ScheduleOn(time.Date(2025, 3, 22, 23, 30, 0, 0, tokyo))
+	if !makeup.IsMakeup() || !makeup.Date.Equal(time.Date(2025, 3, 22, 0, 0, 0, 0, tokyo)) || !makeup.Follows.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, tokyo)) {
+		t.Errorf("ScheduleOn(03-22 23:30 UTC+9) = %+v, want 按 03-14 上課", makeup)
+	}
+	if normal := sdtbu.ScheduleOn(time.Date(2025, 3, 20, 12, 0, 0, 0, tokyo)); normal.IsMakeup() || normal.NoClass {
+		t.Errorf("普通日期 = %+v", normal)
+	}
+}
@@ -0,0 +1,191 @@
+package sdtbu
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTimeLayout 是 iCalendar 中 UTC 時間的格式
+const icsTimeLayout = "20060102T150405Z"
+
+// ExportICS 將課表導出為 iCalendar 格式，每次上課與每場考試各對應一個 VEVENT。
+// 日期由教學週與上課星期計算並套用假期與調休，時間取自當天生效的作息。
+// UID 只由學期、課程名稱、週次和該課程在本週的次序決定，不包含星期、節次、地點和教師，
+// 因此調到同一週的其他時間、換教室或換教師後重新導入會更新原有事件而不是產生重複事件。
+func ExportICS(w io.Writer, timetable *Timetable) error {
+	bw := bufio.NewWriter(w)
+	stamp := timetable.FetchedAt.UTC().Format(icsTimeLayout)
+
+	writeICSLine(bw, "BEGIN:VCALENDAR")
+	writeICSLine(bw, "VERSION:2.0")
+	writeICSLine(bw, "PRODID:-//CourseTool//SDTBU Timetable//ZH")
+	writeICSLine(bw, "CALSCALE:GREGORIAN")
+	writeICSLine(bw, "METHOD:PUBLISH")
+	writeICSLine(bw, "X-WR-CALNAME:"+escapeICSText("智慧山商課表 "+timetable.Semester.String()))
+
+	weeks := make([]int, 0, len(timetable.Weeks))
+	for week := range timetable.Weeks {
+		weeks = append(weeks, week)
+	}
+	sort.Ints(weeks)
+
+	// 逐日導出以套用假期與調休：假期不產生事件，調休日按所跟隨日期的課表產生事件
+	for _, week := range weeks {
+		weekCourses := timetable.CoursesInWeek(timetable.Semester.WeekStart(week))
+		for offset := 0; offset < 7; offset++ {
+			date := timetable.Semester.WeekStart(week).AddDate(0, 0, offset)
+			day := ScheduleOn(date)
+			courses := timetable.CoursesOn(date)
+			for i := range courses {
+				course := &courses[i]
+				if err := writeCourseEvent(bw, timetable, course, week, occurrenceIndex(weekCourses, course), date, &day, stamp); err != nil {
+					currentLogger().Warnf("跳過無法確定時間的課程 %s (第 %d 週): %v", course.Name, week, err)
+				}
+			}
+		}
+	}
+
+	examsPerCourse := make(map[string]int) // 同一門課程有多場考試時 (例如期中、期末) 區分 UID
+	for i := range timetable.Exams {
+		exam := &timetable.Exams[i]
+		examsPerCourse[exam.Course]++
+		description := "考試"
+		if exam.Seat != "" {
+			description += "\n座位號: " + exam.Seat
+		}
+
+		writeICSLine(bw, "BEGIN:VEVENT")
+		writeICSLine(bw, "UID:"+examUID(&timetable.Semester, exam, examsPerCourse[exam.Course]))
+		writeICSLine(bw, "DTSTAMP:"+stamp)
+		writeICSLine(bw, "DTSTART:"+exam.Start.UTC().Format(icsTimeLayout))
+		writeICSLine(bw, "DTEND:"+exam.End.UTC().Format(icsTimeLayout))
+		writeICSLine(bw, "SUMMARY:"+escapeICSText("【考試】"+exam.Course))
+		if exam.Location != "" {
+			writeICSLine(bw, "LOCATION:"+escapeICSText(exam.Location))
+		}
+		writeICSLine(bw, "DESCRIPTION:"+escapeICSText(description))
+		writeICSLine(bw, "END:VEVENT")
+	}
+
+	writeICSLine(bw, "END:VCALENDAR")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("寫入 iCalendar 數據失敗: %w", err)
+	}
+	return nil
+}
+
+// writeCourseEvent 寫入課程在指定日期的一次上課，index 是該次上課在本週同名課程中的次序。
+// 調休日的事件使用包含日期的 UID，避免與所跟隨日期的原有事件衝突。
+func writeCourseEvent(bw *bufio.Writer, timetable *Timetable, course *Course, week, index int, date time.Time, day *DaySchedule, stamp string) error {
+	start, end, err := CourseTimeRange(course, date)
+	if err != nil {
+		return err
+	}
+
+	uid := courseOccurrenceUID(&timetable.Semester, course, week, index)
+	courseWeek := week
+	if day.IsMakeup() {
+		courseWeek = timetable.Semester.WeekOf(day.Follows) // 與課程所屬的教學週一致
+	}
+	description := fmt.Sprintf("教師: %s\n節次: %s\n教學週: 第 %d 週", course.Teacher, course.LessonLabel(), courseWeek)
+	if day.IsMakeup() {
+		description += fmt.Sprintf("\n調休: %s (按 %s 的課表上課)", day.Reason, day.Follows.Format("2006-01-02"))
+		uid = makeupOccurrenceUID(&timetable.Semester, course, date)
+	}
+
+	writeICSLine(bw, "BEGIN:VEVENT")
+	writeICSLine(bw, "UID:"+uid)
+	writeICSLine(bw, "DTSTAMP:"+stamp)
+	writeICSLine(bw, "DTSTART:"+start.UTC().Format(icsTimeLayout))
+	writeICSLine(bw, "DTEND:"+end.UTC().Format(icsTimeLayout))
+	writeICSLine(bw, "SUMMARY:"+escapeICSText(course.Name))
+	if course.Location != "" {
+		writeICSLine(bw, "LOCATION:"+escapeICSText(course.Location))
+	}
+	writeICSLine(bw, "DESCRIPTION:"+escapeICSText(description))
+	writeICSLine(bw, "END:VEVENT")
+	return nil
+}
+
+// WriteICSFile 將課表導出為 .ics 文件
+func WriteICSFile(path string, timetable *Timetable) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("創建 iCalendar 文件 %s 失敗: %w", path, err)
+	}
+	if err := ExportICS(file, timetable); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("關閉 iCalendar 文件 %s 失敗: %w", path, err)
+	}
+	return nil
+}
+
+// occurrenceIndex 返回課程在本週同名課程中的次序 (從 1 開始，按星期與節次排列)。
+// 次序由本週的課表決定而不受假期影響，調課改變星期或節次時通常保持不變。
+func occurrenceIndex(weekCourses []Course, course *Course) int {
+	index := 1
+	for i := range weekCourses {
+		other := &weekCourses[i]
+		if other.Name != course.Name {
+			continue
+		}
+		if other.Weekday < course.Weekday || other.Weekday == course.Weekday && other.StartLesson < course.StartLesson {
+			index++
+		}
+	}
+	return index
+}
+
+// courseOccurrenceUID 為某門課程在某一週的第 index 次上課生成穩定的 UID
+func courseOccurrenceUID(semester *Semester, course *Course, week, index int) string {
+	key := fmt.Sprintf("%s|%s|%s|%d|%d", semester.SchoolYear, semester.Term, course.Name, week, index)
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:]) + "@coursetool.sdtbu"
+}
+
+// makeupOccurrenceUID 為課程在調休日的上課生成穩定的 UID
+func makeupOccurrenceUID(semester *Semester, course *Course, date time.Time) string {
+	key := fmt.Sprintf("%s|%s|makeup|%s|%d|%d|%s", semester.SchoolYear, semester.Term, course.Name, course.StartLesson, course.EndLesson, date.Format("2006-01-02"))
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:]) + "@coursetool.sdtbu"
+}
+
+// examUID 為課程的第 n 場考試生成穩定的 UID，考試時間、考場或座位變化時重新導入會更新原有事件
+func examUID(semester *Semester, exam *Exam, n int) string {
+	key := fmt.Sprintf("%s|%s|exam|%s|%d", semester.SchoolYear, semester.Term, exam.Course, n)
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:]) + "@coursetool.sdtbu"
+}
+
+// escapeICSText 按 RFC 5545 轉義文本值中的特殊字符
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// writeICSLine 寫入一行內容，超過 75 字節時按 RFC 5545 折行，且不會截斷 UTF-8 字符
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // 續行開頭的空格也計入長度
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,66 @@
+package sdtbu_test
+
+import (
+	"CourseTool/sdtbu"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportUIDs 導出課表並返回各事件的 UID 與開始時間
+func exportUIDs(t *testing.T, timetable *sdtbu.Timetable) map[string]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sdtbu.ExportICS(&buf, timetable); err != nil {
+		t.Fatal(err)
+	}
+	events := make(map[string]string)
+	var uid string
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		switch {
+		case strings.HasPrefix(line, "UID:"):
+			uid = strings.TrimPrefix(line, "UID:")
+		case strings.HasPrefix(line, "DTSTART:"):
+			events[uid] = strings.TrimPrefix(line, "DTSTART:")
+		}
+	}
+	return events
+}
+
+func TestExportICSRescheduleKeepsUID(t *testing.T) {
+	semester := sdtbu.Semester{SchoolYear: "2024-2025", Term: "2", StartDate: semesterStart, Weeks: 1}
+	timetable := &sdtbu.Timetable{
+		Semester:  semester,
+		FetchedAt: time.Date(2025, 2, 20, 12, 0, 0, 0, time.Local),
+		Weeks: map[int][]sdtbu.Course{1: {
+			{Name: "高等數學", Weekday: 1, StartLesson: 1, EndLesson: 2, Location: "1號教學樓101"},
+			{Name: "高等數學", Weekday: 4, StartLesson: 3, EndLesson: 4, Location: "1號教學樓101"},
+		}},
+	}
+	before := exportUIDs(t, timetable)
+	if len(before) != 2 {
+		t.Fatalf("導出了 %d 個事件，want 2", len(before))
+	}
+
+	// 星期一的課調到星期二下午並換教室
+	timetable.Weeks[1][0] = sdtbu.Course{Name: "高等數學", Weekday: 2, StartLesson: 5, EndLesson: 6, Location: "2號教學樓203"}
+	after := exportUIDs(t, timetable)
+
+	if !reflect.DeepEqual(keys(before), keys(after)) {
+		t.Errorf("調課後 UID 改變了: %v -> %v", keys(before), keys(after))
+	}
+	if reflect.DeepEqual(before, after) {
+		t.Error("調課後事件的開始時間沒有更新")
+	}
+}
+
+// keys 返回 map 的鍵集合
+func keys(m map[string]string) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for key := range m {
+		set[key] = true
+	}
+	return set
+}
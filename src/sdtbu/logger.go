@@ -0,0 +1,49 @@
+package sdtbu
+
+import "sync"
+
+// Logger 是 sdtbu 輸出運行資訊使用的日誌接口。sdtbu 本身不打印任何內容，
+// 也不在消息中加入時間戳或顏色，格式化與著色由調用方 (例如 CLI) 負責。
+type Logger interface {
+	Debugf(format string, args ...interface{}) // 請求狀態等排查問題時才需要的細節
+	Infof(format string, args ...interface{})  // 登入、獲取課表等正常進度
+	Warnf(format string, args ...interface{})  // 可以自動恢復的問題，例如會話失效或跳過無效條目
+}
+
+// nopLogger 丟棄所有日誌，是未設定 Logger 時的預設值
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+var (
+	loggerMu  sync.RWMutex
+	pkgLogger Logger = nopLogger{}
+)
+
+// SetLogger 設定 sdtbu 的預設日誌輸出，傳入 nil 時不輸出任何日誌。
+// ClientSession.Logger 不為空時，該會話的日誌使用 ClientSession.Logger。
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	loggerMu.Lock()
+	pkgLogger = l
+	loggerMu.Unlock()
+}
+
+// currentLogger 返回預設的日誌輸出
+func currentLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return pkgLogger
+}
+
+// log 返回該會話使用的日誌輸出
+func (cs *ClientSession) log() Logger {
+	if cs.Logger != nil {
+		return cs.Logger
+	}
+	return currentLogger()
+}
@@ -0,0 +1,134 @@
+package sdtbu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Login 可能返回的哨兵錯誤，調用方可以使用 errors.Is 判斷登入失敗的原因
+var (
+	ErrBadCredentials    = errors.New("bad credentials")    // 帳號或密碼錯誤
+	ErrAccountLocked     = errors.New("account locked")     // 帳號因多次嘗試失敗而被鎖定
+	ErrCaptchaRequired   = errors.New("captcha required")   // CAS 要求輸入驗證碼
+	ErrPortalUnavailable = errors.New("portal unavailable") // 門戶或 CAS 無法訪問 (網絡錯誤或 5xx)
+	ErrUnexpectedPage    = errors.New("unexpected page")    // 返回了無法識別的頁面
+)
+
+// 錯誤訊息中用於判斷失敗原因的關鍵字 (同時包含簡體與繁體)
+var (
+	lockedKeywords      = []string{"锁定", "鎖定", "冻结", "凍結", "locked"}
+	captchaKeywords     = []string{"验证码", "驗證碼", "captcha"}
+	credentialsKeywords = []string{"密码", "密碼", "用户名", "用戶名", "账号", "帳號", "password", "username", "credentials"}
+)
+
+// isLoginPage 判斷 URL 是否為 CAS 登入頁面或 WebVPN 網關的登入頁面 (WebVPN 會話失效時重定向到 /login)
+func isLoginPage(resp *http.Response) bool {
+	path := resp.Request.URL.Path
+	return strings.Contains(path, "/cas/login") || strings.Contains(path, "/authserver/login") || path == "/login"
+}
+
+// portalUnavailableError 將網絡錯誤或 5xx 響應包裝為 ErrPortalUnavailable
+func portalUnavailableError(stage string, cause error) error {
+	return fmt.Errorf("%s: %w: %w", stage, ErrPortalUnavailable, cause)
+}
+
+// checkPortalStatus 檢查響應狀態碼，5xx 視為門戶不可用
+func checkPortalStatus(stage string, resp *http.Response) error {
+	if resp.StatusCode >= 500 {
+		return portalUnavailableError(stage, fmt.Errorf("HTTP %s", resp.Status))
+	}
+	return nil
+}
+
+// classifyLoginResponse 根據提交登入表單後的最終響應判斷登入是否成功。
+// 成功時返回 nil；仍停留在 CAS 頁面時解析頁面上的錯誤提示，返回對應的哨兵錯誤。
+func classifyLoginResponse(resp *http.Response, htmlBody string) error {
+	if err := checkPortalStatus("提交登入表單", resp); err != nil {
+		return err
+	}
+
+	stillOnLoginForm := isLoginPage(resp) || ExtractLoginParameters(htmlBody).Lt != ""
+	if !stillOnLoginForm {
+		if strings.Contains(resp.Request.URL.Path, "/tp_up/") {
+			return nil // 已被重定向回門戶，登入成功
+		}
+		return fmt.Errorf("登入後到達了未知頁面 %s: %w", resp.Request.URL.String(), ErrUnexpectedPage)
+	}
+
+	message := extractLoginErrorMessage(htmlBody)
+	switch {
+	case containsAny(message, lockedKeywords):
+		return fmt.Errorf("登入失敗，帳號已被鎖定 (%s): %w", message, ErrAccountLocked)
+	case containsAny(message, captchaKeywords) || (message == "" && hasCaptchaField(htmlBody)):
+		return fmt.Errorf("登入失敗，需要輸入驗證碼 (%s): %w", message, ErrCaptchaRequired)
+	case containsAny(message, credentialsKeywords):
+		return fmt.Errorf("登入失敗，帳號或密碼錯誤 (%s): %w", message, ErrBadCredentials)
+	default:
+		return fmt.Errorf("登入失敗，CAS 返回了無法識別的提示 '%s': %w", message, ErrUnexpectedPage)
+	}
+}
+
+// extractLoginErrorMessage 從 CAS 登入頁面中提取錯誤提示文字
+func extractLoginErrorMessage(htmlBody string) string {
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return ""
+	}
+
+	var message string
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if message != "" {
+			return
+		}
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				isErrorID := attr.Key == "id" && (attr.Val == "errormsg" || attr.Val == "msg" || attr.Val == "errorMsg")
+				isErrorClass := attr.Key == "class" && (strings.Contains(attr.Val, "errors") || strings.Contains(attr.Val, "auth_error") || strings.Contains(attr.Val, "login_error"))
+				if isErrorID || isErrorClass {
+					if text := strings.TrimSpace(nodeText(n)); text != "" {
+						message = text
+						return
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return message
+}
+
+// hasCaptchaField 判斷登入頁面是否包含驗證碼輸入框
+func hasCaptchaField(htmlBody string) bool {
+	return findCaptchaChallenge(htmlBody, nil) != nil
+}
+
+// nodeText 返回節點內所有文本內容
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// containsAny 判斷文本是否包含任意一個關鍵字 (不區分大小寫)
+func containsAny(text string, keywords []string) bool {
+	lower := strings.ToLower(text)
+	for _, keyword := range keywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
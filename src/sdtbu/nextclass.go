@@ -0,0 +1,93 @@
+package sdtbu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultLookaheadDays 是查找下一節課時預設向後查找的天數
+const DefaultLookaheadDays = 14
+
+// ErrNoUpcomingClass 表示在查找範圍內沒有課程 (例如假期或學期已結束)
+var ErrNoUpcomingClass = errors.New("no upcoming class")
+
+// ClassOccurrence 是課程在某一天的一次上課
+type ClassOccurrence struct {
+	Course Course    // 課程，Remark 中說明是否為明天或之後的首節課程
+	Date   time.Time // 上課日期 (本地時區零點)
+	Week   int       // 課程所屬的教學週，調休日為所跟隨日期的教學週
+	Start  time.Time // 開始時刻
+	End    time.Time // 結束時刻
+}
+
+// IsToday 判斷該次上課是否在 now 當天
+func (o *ClassOccurrence) IsToday(now time.Time) bool {
+	return o.Date.Year() == now.Year() && o.Date.YearDay() == now.YearDay()
+}
+
+// WeekFetcher 獲取課表中缺少的教學週，返回排序後的課程列表
+type WeekFetcher func(ctx context.Context, week int) ([]Course, error)
+
+// NextOccurrence 從 now 起向後查找最多 days 天 (不含今天)，返回下一節尚未結束的課程及其日期。
+// 查找已套用假期與調休，可以跨越週末與教學週。遇到課表中缺少的教學週時調用 fetch 獲取並寫入課表，
+// fetch 為 nil 時視為該週沒有課程；學期範圍以外的日期不會觸發獲取。
+// 查找範圍內沒有課程時返回 ErrNoUpcomingClass。
+// 該方法可能修改 tt.Weeks，併發使用同一份課表時調用方需要加鎖。
+func (tt *Timetable) NextOccurrence(ctx context.Context, now time.Time, days int, fetch WeekFetcher) (*ClassOccurrence, error) {
+	if days < 0 {
+		days = 0
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	for offset := 0; offset <= days; offset++ {
+		date := today.AddDate(0, 0, offset)
+		day := ScheduleOn(date)
+		if day.NoClass {
+			continue
+		}
+		if err := tt.ensureWeek(ctx, tt.Semester.WeekOf(day.Follows), fetch); err != nil {
+			return nil, err
+		}
+
+		courses := tt.CoursesOn(date)
+		for i := range courses {
+			start, end, err := CourseTimeRange(&courses[i], date)
+			if err != nil {
+				continue // 跳過此課程，如果找不到時間表資訊
+			}
+			if !now.Before(end) {
+				continue // 今天已經結束的課程
+			}
+
+			occurrence := &ClassOccurrence{Course: courses[i], Date: date, Week: tt.Semester.WeekOf(day.Follows), Start: start, End: end}
+			switch offset {
+			case 0:
+			case 1:
+				occurrence.Course.Remark = "明天的首節課程"
+			default:
+				occurrence.Course.Remark = fmt.Sprintf("%s %s 的首節課程", date.Format("01-02"), weekdayLabel(goWeekdayToApiSkxq(date.Weekday())))
+			}
+			return occurrence, nil
+		}
+	}
+
+	return nil, fmt.Errorf("未來 %d 天內都沒有課程了: %w", days, ErrNoUpcomingClass)
+}
+
+// ensureWeek 在課表缺少學期內的某一週時調用 fetch 獲取該週的課程
+func (tt *Timetable) ensureWeek(ctx context.Context, week int, fetch WeekFetcher) error {
+	if fetch == nil || tt.HasWeek(week) || week < 1 || week > tt.Semester.Weeks {
+		return nil
+	}
+	courses, err := fetch(ctx, week)
+	if err != nil {
+		return err
+	}
+	if tt.Weeks == nil {
+		tt.Weeks = make(map[int][]Course)
+	}
+	tt.Weeks[week] = courses
+	return nil
+}
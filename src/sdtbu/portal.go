@@ -0,0 +1,230 @@
+package sdtbu
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PortalMode 決定門戶請求如何到達智慧山商
+type PortalMode string
+
+const (
+	PortalAuto   PortalMode = "auto"   // 根據登入後的最終 URL 自動判斷直連或 WebVPN (預設)
+	PortalDirect PortalMode = "direct" // 直接訪問校內地址，適用於校園網
+	PortalWebVPN PortalMode = "webvpn" // 通過 WebVPN 的 URL 改寫訪問，適用於校外
+	PortalCustom PortalMode = "custom" // 使用自訂的基礎 URL，例如測試用的門戶
+)
+
+// 智慧山商門戶與 WebVPN 的預設地址
+const (
+	DefaultPortalURL  = "https://zhss.sdtbu.edu.cn/tp_up/"
+	DefaultWebVPNHost = "https://webvpn.sdtbu.edu.cn"
+	DefaultWebVPNKey  = "wrdvpnisthebest!" // WebVPN 改寫主機名時使用的 AES 金鑰與 IV
+)
+
+// PortalResolver 將校內 URL 轉換為實際請求的 URL。
+// sdtbu 中所有門戶地址都通過它構建，而不是直接拼接字串。
+type PortalResolver struct {
+	Mode       PortalMode
+	BaseURL    string // custom 模式下替代 DefaultPortalURL 的基礎 URL
+	WebVPNHost string // webvpn 模式下 WebVPN 的地址，為空時使用 DefaultWebVPNHost
+	WebVPNKey  string // WebVPN 的加密金鑰，為空時使用 DefaultWebVPNKey
+}
+
+// ParsePortalMode 解析 PORTAL_MODE 設定，空字串視為 auto
+func ParsePortalMode(s string) (PortalMode, error) {
+	switch mode := PortalMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return PortalAuto, nil
+	case PortalAuto, PortalDirect, PortalWebVPN, PortalCustom:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("未知的門戶模式 '%s' (可選 auto、direct、webvpn、custom)", s)
+	}
+}
+
+// NewPortalResolver 創建並檢查門戶地址解析器
+func NewPortalResolver(mode PortalMode, baseURL, webVPNHost string) (*PortalResolver, error) {
+	r := &PortalResolver{Mode: mode, BaseURL: baseURL, WebVPNHost: webVPNHost}
+	switch mode {
+	case PortalCustom:
+		if baseURL == "" {
+			return nil, fmt.Errorf("custom 門戶模式需要設定基礎 URL")
+		}
+		if !strings.HasSuffix(r.BaseURL, "/") {
+			r.BaseURL += "/"
+		}
+		if _, err := parseAbsoluteURL(r.BaseURL); err != nil {
+			return nil, err
+		}
+	case PortalWebVPN:
+		if r.WebVPNHost == "" {
+			r.WebVPNHost = DefaultWebVPNHost
+		}
+		if _, err := parseAbsoluteURL(r.WebVPNHost); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// PortalURL 返回門戶中相對於 tp_up/ 的頁面地址，例如 "view?m=up"
+func (r *PortalResolver) PortalURL(path string) (string, error) {
+	return r.Resolve(DefaultPortalURL + path)
+}
+
+// WidgetURL 返回門戶 widgets 接口的地址。
+// WebVPN 下 AJAX 請求需要附加 vpn-12-o2-<主機名> 查詢參數，以便 WebVPN 識別原始主機。
+func (r *PortalResolver) WidgetURL(name string) (string, error) {
+	resolved, err := r.PortalURL("up/widgets/" + name)
+	if err != nil || r.Mode != PortalWebVPN {
+		return resolved, err
+	}
+	internal, _ := url.Parse(DefaultPortalURL)
+	separator := "?"
+	if strings.Contains(resolved, "?") {
+		separator = "&"
+	}
+	return resolved + separator + "vpn-12-o2-" + internal.Host, nil
+}
+
+// Resolve 將任意校內 URL 轉換為當前模式下實際請求的 URL
+func (r *PortalResolver) Resolve(internal string) (string, error) {
+	switch r.Mode {
+	case PortalWebVPN:
+		return EncodeWebVPNURL(r.WebVPNHost, internal, r.WebVPNKey)
+	case PortalCustom:
+		return rebaseURL(r.BaseURL, internal)
+	default:
+		if _, err := parseAbsoluteURL(internal); err != nil {
+			return "", err
+		}
+		return internal, nil
+	}
+}
+
+// rebaseURL 將校內 URL 映射到自訂的基礎 URL：門戶下的路徑相對於 base 解析，
+// 其他地址 (例如 CAS) 只替換協議與主機。
+func rebaseURL(base, internal string) (string, error) {
+	baseURL, err := parseAbsoluteURL(base)
+	if err != nil {
+		return "", err
+	}
+	if rest, ok := strings.CutPrefix(internal, DefaultPortalURL); ok {
+		ref, err := url.Parse(rest)
+		if err != nil {
+			return "", fmt.Errorf("無效的 URL '%s': %w", internal, err)
+		}
+		return baseURL.ResolveReference(ref).String(), nil
+	}
+	target, err := parseAbsoluteURL(internal)
+	if err != nil {
+		return "", err
+	}
+	target.Scheme = baseURL.Scheme
+	target.Host = baseURL.Host
+	return target.String(), nil
+}
+
+// EncodeWebVPNURL 按 WebVPN (wrdvpn) 的規則改寫校內 URL：
+// 主機名使用 AES-128-CFB 加密後以十六進制表示，並在前面加上 IV 的十六進制，
+// 改寫後的地址為 <vpnHost>/<協議>[-<端口>]/<加密主機名><路徑>?<查詢>。
+// key 為空時使用 DefaultWebVPNKey，IV 與金鑰相同。
+func EncodeWebVPNURL(vpnHost, internal, key string) (string, error) {
+	if key == "" {
+		key = DefaultWebVPNKey
+	}
+	target, err := parseAbsoluteURL(internal)
+	if err != nil {
+		return "", err
+	}
+	vpn, err := parseAbsoluteURL(vpnHost)
+	if err != nil {
+		return "", err
+	}
+
+	encryptedHost, err := encryptWebVPNHost(target.Hostname(), key)
+	if err != nil {
+		return "", err
+	}
+	segment := target.Scheme
+	if port := target.Port(); port != "" {
+		segment += "-" + port
+	}
+
+	prefix := "/" + segment + "/" + encryptedHost
+	rewritten := &url.URL{
+		Scheme:   vpn.Scheme,
+		Host:     vpn.Host,
+		Path:     prefix + target.Path,
+		RawPath:  prefix + target.EscapedPath(), // 保留原始路徑的轉義形式
+		RawQuery: target.RawQuery,
+		Fragment: target.Fragment,
+	}
+	return rewritten.String(), nil
+}
+
+// encryptWebVPNHost 加密主機名，返回 hex(IV) + hex(密文)。
+// WebVPN 要求 CFB 模式以兼容其 JavaScript 實現，這裡僅用於構造地址而非保護數據。
+func encryptWebVPNHost(host, key string) (string, error) {
+	if len(key) != aes.BlockSize {
+		return "", fmt.Errorf("WebVPN 金鑰長度必須為 %d 字節", aes.BlockSize)
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("初始化 WebVPN 加密失敗: %w", err)
+	}
+	iv := []byte(key)
+	encrypted := make([]byte, len(host))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(encrypted, []byte(host))
+	return hex.EncodeToString(iv) + hex.EncodeToString(encrypted), nil
+}
+
+// parseAbsoluteURL 解析並檢查絕對 URL
+func parseAbsoluteURL(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("無效的 URL '%s'", raw)
+	}
+	return parsed, nil
+}
+
+// portal 返回當前會話使用的地址解析器。auto 模式下根據登入後的最終 URL 判斷：
+// 最終 URL 的主機不是門戶主機時，視為經由該主機的 WebVPN 訪問。
+func (cs *ClientSession) portal() *PortalResolver {
+	if cs.portalMode() != PortalAuto {
+		return cs.Resolver
+	}
+	base := cs.baseURL()
+	if base == "" {
+		return &PortalResolver{Mode: PortalDirect}
+	}
+	loggedIn, err := url.Parse(base)
+	internal, _ := url.Parse(DefaultPortalURL)
+	if err != nil || loggedIn.Host == internal.Host {
+		return &PortalResolver{Mode: PortalDirect}
+	}
+	return &PortalResolver{Mode: PortalWebVPN, WebVPNHost: loggedIn.Scheme + "://" + loggedIn.Host}
+}
+
+// portalMode 返回配置的門戶模式，未配置時為 auto
+func (cs *ClientSession) portalMode() PortalMode {
+	if cs.Resolver == nil || cs.Resolver.Mode == "" {
+		return PortalAuto
+	}
+	return cs.Resolver.Mode
+}
+
+// portalURL 構建門戶頁面的完整 URL
+func (cs *ClientSession) portalURL(path string) (string, error) {
+	return cs.portal().PortalURL(path)
+}
+
+// widgetURL 構建門戶 widgets 接口的完整 URL
+func (cs *ClientSession) widgetURL(name string) (string, error) {
+	return cs.portal().WidgetURL(name)
+}
@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redacted 是替換敏感內容的佔位符
+const redacted = "REDACTED"
+
+// sensitiveKeys 是需要脫敏的查詢參數與表單字段 (小寫)：
+// CAS 的 ticket、加密後的帳號密碼 rsa 及其長度、驗證碼答案與微信的憑證
+var sensitiveKeys = map[string]bool{
+	"ticket": true, "rsa": true, "ul": true, "pl": true,
+	"username": true, "password": true, "access_token": true, "appid": true, "secret": true,
+}
+
+// isSensitiveKey 判斷參數是否需要脫敏
+func isSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	return sensitiveKeys[lower] || strings.Contains(lower, "captcha") || strings.Contains(lower, "vcode")
+}
+
+// redactValues 把敏感參數的值替換為佔位符
+func redactValues(values url.Values) url.Values {
+	for key := range values {
+		if isSensitiveKey(key) {
+			for i := range values[key] {
+				values[key][i] = redacted
+			}
+		}
+	}
+	return values
+}
+
+// redactURL 脫敏 URL 中的敏感查詢參數、用戶信息與 Secrets
+func (r *Recorder) redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	if clean.RawQuery != "" {
+		if values, err := url.ParseQuery(clean.RawQuery); err == nil {
+			clean.RawQuery = redactValues(values).Encode()
+		}
+	}
+	return r.redactSecrets(clean.String())
+}
+
+// redactBody 脫敏請求或響應主體：表單字段按名稱處理，其他內容只替換 Secrets
+func (r *Recorder) redactBody(body, contentType string) string {
+	if body != "" && strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(body); err == nil {
+			body = redactValues(values).Encode()
+		}
+	}
+	return r.redactSecrets(body)
+}
+
+// redactSecrets 將 Secrets 中的字串替換為佔位符，過短的字串不處理以免誤傷正常內容
+func (r *Recorder) redactSecrets(s string) string {
+	for _, secret := range r.Secrets {
+		if len(secret) >= 4 {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+// redactHeader 移除請求中攜帶憑證的標頭
+func redactHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	clean.Del("Cookie")
+	clean.Del("Authorization")
+	return clean
+}
+
+// redactResponseHeader 把 Set-Cookie 的值替換為佔位符 (保留名稱與路徑，回放時仍能建立會話)，
+// 並脫敏重定向地址中的 ticket
+func (r *Recorder) redactResponseHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	if cookies := (&http.Response{Header: header}).Cookies(); len(cookies) > 0 {
+		clean.Del("Set-Cookie")
+		for _, cookie := range cookies {
+			replaced := &http.Cookie{Name: cookie.Name, Value: redacted, Path: cookie.Path}
+			clean.Add("Set-Cookie", replaced.String())
+		}
+	}
+	if location := clean.Get("Location"); location != "" {
+		if parsed, err := url.Parse(location); err == nil {
+			clean.Set("Location", r.redactURL(parsed))
+		}
+	}
+	return clean
+}
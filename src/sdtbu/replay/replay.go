@@ -0,0 +1,282 @@
+// Package replay 錄製與回放門戶的 HTTP 流量。
+// Recorder 把每次請求與響應脫敏後保存為 fixture 目錄中的 JSON 文件，
+// Player 讀取這些文件並按請求返回錄製的響應，無需帳號密碼即可重現解析或排課問題。
+package replay
+
+import (
+	"CourseTool/logging"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRequestMismatch 表示 Strict 回放時請求的查詢或主體與同一路徑的錄製不同
+var ErrRequestMismatch = errors.New("request does not match recording")
+
+// logger 返回 replay 的日誌輸出
+func logger() *slog.Logger {
+	return logging.Component("replay")
+}
+
+// Exchange 是 fixture 目錄中保存的一次請求與響應 (已脫敏)
+type Exchange struct {
+	Seq            int         `json:"seq"`            // 錄製順序
+	RecordedAt     time.Time   `json:"recordedAt"`     // 錄製時間，回放時可據此設定模擬的當前時間
+	Method         string      `json:"method"`         // 請求方法
+	URL            string      `json:"url"`            // 請求的完整 URL
+	RequestHeader  http.Header `json:"requestHeader"`  // 請求標頭
+	RequestBody    string      `json:"requestBody"`    // 請求主體
+	Status         int         `json:"status"`         // 響應狀態碼
+	ResponseHeader http.Header `json:"responseHeader"` // 響應標頭
+	ResponseBody   string      `json:"responseBody"`   // 響應主體
+}
+
+// Recorder 是錄製流量的 http.RoundTripper
+type Recorder struct {
+	Base    http.RoundTripper // 實際發送請求的 RoundTripper，為 nil 時使用 http.DefaultTransport
+	Dir     string            // fixture 目錄
+	Secrets []string          // 需要從 URL 與主體中抹去的字串，例如學號與密碼
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder 創建錄製到 dir 的 Recorder，目錄中已有的錄製會被保留，新的錄製接在其後
+func NewRecorder(dir string, base http.RoundTripper, secrets ...string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("創建 fixture 目錄失敗: %w", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("讀取 fixture 目錄失敗: %w", err)
+	}
+	return &Recorder{Base: base, Dir: dir, Secrets: secrets, seq: len(existing)}, nil
+}
+
+// RoundTrip 發送請求並把脫敏後的請求與響應寫入 fixture 目錄
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("replay: 讀取請求主體失敗: %w", err)
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(reqBody)), nil
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("replay: 讀取響應主體失敗: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.seq++
+	seq := r.seq
+	r.mu.Unlock()
+
+	exchange := &Exchange{
+		Seq:            seq,
+		RecordedAt:     time.Now(),
+		Method:         req.Method,
+		URL:            r.redactURL(req.URL),
+		RequestHeader:  redactHeader(req.Header),
+		RequestBody:    r.redactBody(string(reqBody), req.Header.Get("Content-Type")),
+		Status:         resp.StatusCode,
+		ResponseHeader: r.redactResponseHeader(resp.Header),
+		ResponseBody:   r.redactBody(string(respBody), resp.Header.Get("Content-Type")),
+	}
+	if err := r.save(exchange); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save 把一次交換寫入 <序號>-<方法>-<路徑最後一段>.json
+func (r *Recorder) save(exchange *Exchange) error {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return fmt.Errorf("replay: 序列化錄製內容失敗: %w", err)
+	}
+	name := fmt.Sprintf("%04d-%s-%s.json", exchange.Seq, exchange.Method, fileSlug(exchange.URL))
+	if err := os.WriteFile(filepath.Join(r.Dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("replay: 寫入 fixture 失敗: %w", err)
+	}
+	return nil
+}
+
+// fileSlug 取 URL 路徑的最後一段作為文件名的一部分
+func fileSlug(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "request"
+	}
+	segment := filepath.Base(strings.TrimSuffix(parsed.Path, "/"))
+	slug := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, segment)
+	if slug == "" || slug == "_" {
+		return "index"
+	}
+	return slug
+}
+
+// Player 是回放 fixture 的 http.RoundTripper，不會發出任何網絡請求
+type Player struct {
+	// Strict 為 true 時，只有路徑相同而主體不同的請求返回錯誤，而不是回放同一路徑的錄製。
+	// 回放時鐘固定在錄製時間時，請求應與錄製完全一致，主體不同說明程式的行為已經改變。
+	Strict bool
+
+	recordedAt time.Time // 最早一次錄製的時間
+
+	mu     sync.Mutex
+	exact  map[string][]*Exchange // 方法 + 路徑 + 查詢 + 主體 -> 錄製的響應
+	loose  map[string][]*Exchange // 方法 + 路徑 -> 錄製的響應，主體不同 (例如查詢日期變化) 時使用
+	served map[string]int         // 每個鍵已回放的次數
+}
+
+// LoadPlayer 讀取 dir 中的全部錄製
+func LoadPlayer(dir string) (*Player, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("讀取 fixture 目錄失敗: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("fixture 目錄 %s 中沒有錄製文件", dir)
+	}
+
+	exchanges := make([]*Exchange, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("讀取 fixture %s 失敗: %w", file, err)
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(data, &exchange); err != nil {
+			return nil, fmt.Errorf("解析 fixture %s 失敗: %w", file, err)
+		}
+		exchanges = append(exchanges, &exchange)
+	}
+	// 按錄製順序排列，同一請求多次出現時依次回放
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i].Seq < exchanges[j].Seq })
+
+	p := &Player{
+		recordedAt: exchanges[0].RecordedAt,
+		exact:      make(map[string][]*Exchange),
+		loose:      make(map[string][]*Exchange),
+		served:     make(map[string]int),
+	}
+	for _, exchange := range exchanges {
+		parsed, err := url.Parse(exchange.URL)
+		if err != nil {
+			return nil, fmt.Errorf("fixture #%d 的 URL 無效: %w", exchange.Seq, err)
+		}
+		exactKey, looseKey := requestKeys(exchange.Method, parsed, exchange.RequestBody)
+		p.exact[exactKey] = append(p.exact[exactKey], exchange)
+		p.loose[looseKey] = append(p.loose[looseKey], exchange)
+	}
+	return p, nil
+}
+
+// RecordedAt 返回最早一次錄製的時間，回放時可以把模擬的當前時間設為該時刻，使請求中的日期與錄製一致
+func (p *Player) RecordedAt() time.Time {
+	return p.recordedAt
+}
+
+// RoundTrip 返回與請求匹配的錄製響應。先按方法、路徑、查詢與主體精確匹配，
+// 找不到時按方法與路徑匹配並打印警告 (Strict 時返回錯誤)；仍找不到時返回錯誤。主機名不參與匹配。
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("replay: 讀取請求主體失敗: %w", err)
+		}
+		req.Body.Close()
+	}
+
+	// 使用與錄製時相同的規則脫敏，使請求與 fixture 可以比較
+	redactor := &Recorder{}
+	redactedURL, _ := url.Parse(redactor.redactURL(req.URL))
+	exactKey, looseKey := requestKeys(req.Method, redactedURL, redactor.redactBody(string(body), req.Header.Get("Content-Type")))
+
+	exchange := p.next(exactKey, p.exact[exactKey])
+	if exchange == nil {
+		exchange = p.next(looseKey, p.loose[looseKey])
+		if exchange != nil {
+			if p.Strict {
+				return nil, fmt.Errorf("replay: %s %s 的請求與錄製 #%d 不同: %w", req.Method, redactedURL.Redacted(), exchange.Seq, ErrRequestMismatch)
+			}
+			logger().Warn("請求與錄製不同，回放同一路徑的錄製", "method", req.Method, "url", redactedURL.Redacted(), "seq", exchange.Seq)
+		}
+	}
+	if exchange == nil {
+		return nil, fmt.Errorf("replay: 沒有與 %s %s 匹配的錄製", req.Method, redactedURL.Redacted())
+	}
+
+	header := exchange.ResponseHeader.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(exchange.ResponseBody)),
+		ContentLength: int64(len(exchange.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// next 依次返回同一鍵下的錄製，全部回放過後重複最後一個
+func (p *Player) next(key string, candidates []*Exchange) *Exchange {
+	if len(candidates) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	index := p.served[key]
+	p.served[key]++
+	if index >= len(candidates) {
+		index = len(candidates) - 1
+	}
+	return candidates[index]
+}
+
+// requestKeys 返回用於匹配的精確鍵與寬鬆鍵
+func requestKeys(method string, u *url.URL, body string) (exact, loose string) {
+	loose = method + " " + u.EscapedPath()
+	return loose + "?" + u.RawQuery + "\n" + body, loose
+}
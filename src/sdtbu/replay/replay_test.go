@@ -0,0 +1,78 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordOne 通過 Recorder 向 handler 發送一次 JSON POST 請求並返回 fixture 目錄
+func recordOne(t *testing.T, body string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"learnWeek":3}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+	resp, err := client.Post(server.URL+"/tp_up/up/widgets/getLearnweekbyDate", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return dir
+}
+
+// replayOne 通過 player 發送請求並返回響應主體
+func replayOne(player *Player, body string) (string, error) {
+	client := &http.Client{Transport: player}
+	resp, err := client.Post("http://portal.invalid/tp_up/up/widgets/getLearnweekbyDate", "application/json", strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func TestPlayerRecordedAt(t *testing.T) {
+	before := time.Now()
+	player, err := LoadPlayer(recordOne(t, `{"schoolDate":"2025-03-13"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recordedAt := player.RecordedAt(); recordedAt.Before(before.Add(-time.Second)) || recordedAt.After(time.Now()) {
+		t.Errorf("RecordedAt = %s, want around %s", recordedAt, before)
+	}
+}
+
+func TestPlayerBodyMismatch(t *testing.T) {
+	dir := recordOne(t, `{"schoolDate":"2025-03-13"}`)
+
+	player, err := LoadPlayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := replayOne(player, `{"schoolDate":"2025-03-13"}`); err != nil || body != `{"learnWeek":3}` {
+		t.Errorf("精確匹配 = %q, %v", body, err)
+	}
+	// 非 Strict 時回放同一路徑的錄製
+	if body, err := replayOne(player, `{"schoolDate":"2025-03-20"}`); err != nil || body != `{"learnWeek":3}` {
+		t.Errorf("寬鬆匹配 = %q, %v", body, err)
+	}
+
+	player.Strict = true
+	if _, err := replayOne(player, `{"schoolDate":"2025-03-20"}`); !errors.Is(err, ErrRequestMismatch) {
+		t.Errorf("Strict 回放主體不同的請求 error = %v, want ErrRequestMismatch", err)
+	}
+}
@@ -0,0 +1,264 @@
+package sdtbu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSemesterWeeks 是未能從校曆得知教學週總數時使用的預設值
+const DefaultSemesterWeeks = 20
+
+// Semester 結構體描述一個學期：學年、學期序號以及第一教學週的起始日期
+type Semester struct {
+	SchoolYear string    `json:"schoolYear"` // 學年，例如 "2024-2025"
+	Term       string    `json:"semester"`   // 學期序號，"1" 為秋季學期，"2" 為春季學期
+	StartDate  time.Time `json:"startDate"`  // 第一教學週星期一的日期 (本地時區零點)
+	Weeks      int       `json:"weeks"`      // 教學週總數
+}
+
+// WeekOf 返回指定日期所在的教學週 (從 1 開始)。
+// 早於學期開始的日期返回值小於 1。
+func (s *Semester) WeekOf(t time.Time) int {
+	days := daysBetween(s.StartDate, t)
+	if days < 0 {
+		return -((-days + 6) / 7) + 1 // 向下取整，學期開始前一週為第 0 週
+	}
+	return days/7 + 1
+}
+
+// WeekStart 返回指定教學週星期一的日期
+func (s *Semester) WeekStart(week int) time.Time {
+	return s.StartDate.AddDate(0, 0, (week-1)*7)
+}
+
+// Contains 判斷指定日期是否落在該學期的教學週範圍內
+func (s *Semester) Contains(t time.Time) bool {
+	week := s.WeekOf(t)
+	return week >= 1 && week <= s.Weeks
+}
+
+// String 返回學期的簡短描述，例如 "2024-2025 學年第 2 學期"
+func (s *Semester) String() string {
+	return fmt.Sprintf("%s 學年第 %s 學期", s.SchoolYear, s.Term)
+}
+
+// SemesterResolver 負責確定當前學期。
+// 解析順序：Override (測試用) -> 門戶查詢 -> 本地校曆文件。
+type SemesterResolver struct {
+	Override     *Semester // 若設定則直接使用，跳過門戶與校曆
+	CalendarFile string    // 本地校曆 JSON 文件路徑，門戶查詢失敗時作為後備
+}
+
+// calendarEntry 對應校曆文件中的單個學期條目
+type calendarEntry struct {
+	SchoolYear string `json:"schoolYear"`
+	Semester   string `json:"semester"`
+	StartDate  string `json:"startDate"` // 格式 "2006-01-02"
+	Weeks      int    `json:"weeks"`
+}
+
+// LoadAcademicCalendar 從 JSON 文件中讀取校曆。
+// 文件內容為學期條目的數組，例如：
+// [{"schoolYear":"2024-2025","semester":"2","startDate":"2025-02-24","weeks":20}]
+func LoadAcademicCalendar(path string) ([]Semester, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取校曆文件 %s 失敗: %w", path, err)
+	}
+
+	var entries []calendarEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析校曆文件 %s 失敗: %w", path, err)
+	}
+
+	semesters := make([]Semester, 0, len(entries))
+	for _, entry := range entries {
+		start, err := time.ParseInLocation("2006-01-02", entry.StartDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("校曆條目 %s-%s 的開始日期 '%s' 無效: %w", entry.SchoolYear, entry.Semester, entry.StartDate, err)
+		}
+		weeks := entry.Weeks
+		if weeks <= 0 {
+			weeks = DefaultSemesterWeeks
+		}
+		semesters = append(semesters, Semester{
+			SchoolYear: entry.SchoolYear,
+			Term:       entry.Semester,
+			StartDate:  mondayOf(start),
+			Weeks:      weeks,
+		})
+	}
+	return semesters, nil
+}
+
+// semesterFromCalendar 在校曆中查找包含指定日期的學期。
+// 若日期處於假期中，則返回最近一個已經開始的學期；若早於所有學期，則返回最早的學期。
+func semesterFromCalendar(semesters []Semester, date time.Time) (*Semester, bool) {
+	var best *Semester
+	for i := range semesters {
+		s := &semesters[i]
+		if s.Contains(date) {
+			return s, true
+		}
+		if !date.Before(s.StartDate) && (best == nil || s.StartDate.After(best.StartDate)) {
+			best = s
+		}
+	}
+	if best == nil {
+		for i := range semesters {
+			if best == nil || semesters[i].StartDate.Before(best.StartDate) {
+				best = &semesters[i]
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// ResolveSemester 確定指定日期所屬的學期並緩存在會話中。
+// 若已緩存的學期包含該日期，則不會重新查詢。可以在多個 goroutine 中併發調用。
+func (cs *ClientSession) ResolveSemester(ctx context.Context, date time.Time) (*Semester, error) {
+	if semester := cs.cachedSemester(); semester != nil && semester.Contains(date) {
+		return semester, nil
+	}
+
+	resolver := cs.SemesterResolver
+	if resolver == nil {
+		resolver = &SemesterResolver{}
+	}
+
+	if resolver.Override != nil {
+		return cs.setSemester(resolver.Override), nil
+	}
+
+	var calendar []Semester
+	var calendarErr error
+	if resolver.CalendarFile != "" {
+		calendar, calendarErr = LoadAcademicCalendar(resolver.CalendarFile)
+	}
+
+	var semester *Semester
+	portalErr := cs.withRelogin(ctx, func() error {
+		var err error
+		semester, err = cs.fetchSemesterFromPortal(ctx, date)
+		return err
+	})
+	if portalErr == nil {
+		// 門戶不提供教學週總數，若校曆中有相同學期則沿用其週數
+		for _, s := range calendar {
+			if s.SchoolYear == semester.SchoolYear && s.Term == semester.Term {
+				semester.Weeks = s.Weeks
+				break
+			}
+		}
+		return cs.setSemester(semester), nil
+	}
+	if ctx.Err() != nil {
+		return nil, portalErr // 已取消或超時，不再退回校曆
+	}
+
+	cs.log().Warnf("無法從門戶獲取學期資訊，改用本地校曆: %v", portalErr)
+
+	if calendarErr != nil {
+		return nil, fmt.Errorf("無法確定當前學期: 門戶查詢失敗 (%w)，校曆不可用 (%w)", portalErr, calendarErr)
+	}
+	if s, ok := semesterFromCalendar(calendar, date); ok {
+		semester := *s
+		return cs.setSemester(&semester), nil
+	}
+
+	return nil, fmt.Errorf("無法確定當前學期: 門戶查詢失敗 (%w)，且未配置可用的校曆文件", portalErr)
+}
+
+// cachedSemester 返回最近一次解析出的學期，尚未解析時返回 nil
+func (cs *ClientSession) cachedSemester() *Semester {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.semester
+}
+
+// setSemester 緩存解析出的學期並將其返回
+func (cs *ClientSession) setSemester(semester *Semester) *Semester {
+	cs.mu.Lock()
+	cs.semester = semester
+	cs.mu.Unlock()
+	return semester
+}
+
+// fetchSemesterFromPortal 通過 getLearnweekbyDate 接口查詢指定日期的學年、學期與教學週，
+// 並據此推算第一教學週的起始日期。
+func (cs *ClientSession) fetchSemesterFromPortal(ctx context.Context, date time.Time) (*Semester, error) {
+	if cs.baseURL() == "" {
+		return nil, fmt.Errorf("尚未登入，無法查詢學期資訊")
+	}
+
+	// 該接口只查詢教學周，可以安全重試
+	bodyBytes, err := cs.postWidget(ctx, "getLearnweekbyDate", map[string]string{
+		"schoolDate": date.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling getLearnweekbyDate response: %w", err)
+	}
+
+	schoolYear := lookupString(result, []string{"schoolYear", "XN"})
+	term := lookupString(result, []string{"semester", "XQ"})
+	learnWeek, ok := lookupInt(result, []string{"learnWeek", "ZC"})
+	if schoolYear == "" || term == "" || !ok || learnWeek < 1 {
+		return nil, fmt.Errorf("getLearnweekbyDate 響應缺少學期資訊: %s", strings.TrimSpace(string(bodyBytes)))
+	}
+
+	return &Semester{
+		SchoolYear: schoolYear,
+		Term:       term,
+		StartDate:  mondayOf(date).AddDate(0, 0, -(learnWeek-1)*7),
+		Weeks:      DefaultSemesterWeeks,
+	}, nil
+}
+
+// ParseSemesterOverride 解析形如 "2024-2025|2|2025-02-24" 的學期覆蓋設定，
+// 依次為學年、學期序號與第一教學週的開始日期，可選第四段為教學週總數。
+func ParseSemesterOverride(value string) (*Semester, error) {
+	parts := strings.Split(value, "|")
+	if len(parts) < 3 || len(parts) > 4 {
+		return nil, fmt.Errorf("無效的學期覆蓋設定 '%s'。預期格式為 學年|學期|開始日期[|週數]", value)
+	}
+	start, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(parts[2]), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("學期覆蓋設定中的開始日期 '%s' 無效: %w", parts[2], err)
+	}
+	weeks := DefaultSemesterWeeks
+	if len(parts) == 4 {
+		weeks, err = strconv.Atoi(strings.TrimSpace(parts[3]))
+		if err != nil || weeks <= 0 {
+			return nil, fmt.Errorf("學期覆蓋設定中的週數 '%s' 無效", parts[3])
+		}
+	}
+	return &Semester{
+		SchoolYear: strings.TrimSpace(parts[0]),
+		Term:       strings.TrimSpace(parts[1]),
+		StartDate:  mondayOf(start),
+		Weeks:      weeks,
+	}, nil
+}
+
+// mondayOf 返回指定日期所在週的星期一 (本地時區零點)
+func mondayOf(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -(goWeekdayToApiSkxq(day.Weekday()) - 1))
+}
+
+// daysBetween 返回兩個日期之間相差的天數，只比較日期部分，忽略時間與夏令時
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
@@ -0,0 +1,391 @@
+package sdtbu
+
+import (
+	"CourseTool/httpretry"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSessionExpired 表示門戶返回了登入頁面而不是 JSON 數據，會話已經失效
+var ErrSessionExpired = errors.New("portal session expired")
+
+// ErrNoSavedSession 表示磁碟上沒有可用的已保存會話
+var ErrNoSavedSession = errors.New("no saved session")
+
+// savedCookie 是可序列化的 cookie，連同設定它的 URL 一起保存，
+// 以便恢復時重現 host-only cookie 的作用範圍
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+}
+
+// savedSession 是寫入磁碟 (加密前) 的會話內容
+type savedSession struct {
+	BaseURL string        `json:"baseURL"`        // 登入後門戶的基礎 URL (直連或 webVPN)
+	Mode    PortalMode    `json:"mode,omitempty"` // 保存時配置的門戶模式
+	Cookies []savedCookie `json:"cookies"`
+	SavedAt time.Time     `json:"savedAt"`
+}
+
+// persistentJar 包裝標準庫的 cookiejar，並記錄每個設定過的 cookie 以便序列化。
+// 標準庫的 cookiejar 不提供導出所有 cookie 的方法，因此需要自行記錄。
+type persistentJar struct {
+	*cookiejar.Jar
+	mu      sync.Mutex
+	records map[string]savedCookie
+}
+
+// newPersistentJar 創建一個包裝指定 cookiejar 的 persistentJar
+func newPersistentJar(jar *cookiejar.Jar) *persistentJar {
+	return &persistentJar{Jar: jar, records: make(map[string]savedCookie)}
+}
+
+// SetCookies 實現 http.CookieJar 接口，在寫入底層 jar 的同時記錄 cookie
+func (pj *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	pj.Jar.SetCookies(u, cookies)
+
+	pj.mu.Lock()
+	defer pj.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		key := u.Hostname() + "|" + c.Domain + "|" + c.Path + "|" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			delete(pj.records, key) // 服務器要求刪除該 cookie
+			continue
+		}
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		pj.records[key] = savedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+	}
+}
+
+// snapshot 返回所有尚未過期的 cookie 記錄
+func (pj *persistentJar) snapshot() []savedCookie {
+	pj.mu.Lock()
+	defer pj.mu.Unlock()
+	now := time.Now()
+	cookies := make([]savedCookie, 0, len(pj.records))
+	for _, c := range pj.records {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies
+}
+
+// restore 將保存的 cookie 重新寫入 jar
+func (pj *persistentJar) restore(cookies []savedCookie) {
+	now := time.Now()
+	for _, c := range cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		pj.SetCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}})
+	}
+}
+
+// SetCredentials 設定會話失效時用於自動重新登入的帳號密碼
+func (cs *ClientSession) SetCredentials(username, password string) {
+	cs.mu.Lock()
+	cs.username = username
+	cs.password = password
+	cs.mu.Unlock()
+}
+
+// SaveSession 將當前會話的 cookie 與門戶地址加密後寫入 cs.SessionFile。
+// 未設定 SessionFile 時不做任何事。
+func (cs *ClientSession) SaveSession() error {
+	if cs.SessionFile == "" || cs.jar == nil {
+		return nil
+	}
+
+	state := savedSession{
+		BaseURL: cs.baseURL(),
+		Mode:    cs.portalMode(),
+		Cookies: cs.jar.snapshot(),
+		SavedAt: time.Now(),
+	}
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("序列化會話失敗: %w", err)
+	}
+
+	ciphertext, err := sealSession(cs.sessionKey(), plaintext)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(cs.SessionFile); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("創建會話目錄失敗: %w", err)
+		}
+	}
+	if err := writeFileAtomic(cs.SessionFile, ciphertext); err != nil {
+		return fmt.Errorf("寫入會話文件失敗: %w", err)
+	}
+	return nil
+}
+
+// LoadSession 從 cs.SessionFile 讀取並解密之前保存的會話，恢復 cookie 與門戶地址。
+// 文件不存在時返回 ErrNoSavedSession。恢復的會話可能已在服務器端失效，
+// 調用方無需預先驗證：請求時若發現被重定向到登入頁面，會自動重新登入。
+func (cs *ClientSession) LoadSession() error {
+	if cs.SessionFile == "" || cs.jar == nil {
+		return ErrNoSavedSession
+	}
+
+	ciphertext, err := os.ReadFile(cs.SessionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoSavedSession
+	}
+	if err != nil {
+		return fmt.Errorf("讀取會話文件失敗: %w", err)
+	}
+
+	plaintext, err := openSession(cs.sessionKey(), ciphertext)
+	if err != nil {
+		return err
+	}
+
+	var state savedSession
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return fmt.Errorf("解析會話文件失敗: %w", err)
+	}
+	if state.BaseURL == "" || len(state.Cookies) == 0 {
+		return ErrNoSavedSession
+	}
+	if state.Mode == "" {
+		state.Mode = PortalAuto
+	}
+	if state.Mode != cs.portalMode() {
+		// 不同門戶模式下的 cookie 屬於不同主機，無法複用
+		cs.log().Warnf("保存的會話使用 %s 模式，當前為 %s 模式，將重新登入", state.Mode, cs.portalMode())
+		return ErrNoSavedSession
+	}
+
+	cs.jar.restore(state.Cookies)
+	cs.setBaseURL(state.BaseURL)
+
+	cs.log().Infof("已恢復 %s 保存的會話 (%d 個 cookie)", state.SavedAt.Format("2006-01-02 15:04"), len(state.Cookies))
+	return nil
+}
+
+// sessionKey 根據 SessionSecret 或帳號密碼派生會話文件的加密金鑰
+func (cs *ClientSession) sessionKey() []byte {
+	secret := cs.SessionSecret
+	if secret == "" {
+		cs.mu.RLock()
+		secret = cs.username + "\x00" + cs.password
+		cs.mu.RUnlock()
+	}
+	sum := sha256.Sum256([]byte("CourseTool session\x00" + secret))
+	return sum[:]
+}
+
+// sealSession 使用 AES-256-GCM 加密會話數據，隨機 nonce 置於密文之前
+func sealSession(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化會話加密失敗: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化會話加密失敗: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("生成隨機數失敗: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openSession 解密由 sealSession 生成的數據
+func openSession(key, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化會話解密失敗: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化會話解密失敗: %w", err)
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("會話文件已損壞")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密會話文件失敗 (金鑰或帳號可能已變更): %w", err)
+	}
+	return plaintext, nil
+}
+
+// checkJSONResponse 檢查門戶接口的響應是否為 JSON。
+// 只有被重定向到 CAS 登入頁面時才說明會話已經失效，返回 ErrSessionExpired；
+// 5xx 錯誤頁面 (門戶或 WebVPN 網關故障) 返回 ErrPortalUnavailable，其他 HTML 頁面返回 ErrUnexpectedPage，
+// 以免門戶宕機時反覆執行完整的 CAS 登入。
+func checkJSONResponse(resp *http.Response, body []byte) error {
+	finalURL := resp.Request.URL
+	if isLoginPage(resp) {
+		return fmt.Errorf("門戶將請求重定向到了登入頁面 (%s): %w", finalURL.String(), ErrSessionExpired)
+	}
+	if err := checkPortalStatus("門戶接口", resp); err != nil {
+		return err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	trimmed := bytes.TrimSpace(body)
+	if strings.Contains(contentType, "text/html") || (len(trimmed) > 0 && trimmed[0] == '<') {
+		return fmt.Errorf("門戶返回了 HTML 頁面 (%s, HTTP %s): %w", finalURL.String(), resp.Status, ErrUnexpectedPage)
+	}
+	return nil
+}
+
+// withRelogin 執行 fn，若 fn 因會話失效而失敗且已知帳號密碼，則重新登入後再執行一次
+func (cs *ClientSession) withRelogin(ctx context.Context, fn func() error) error {
+	generation := cs.loginGeneration()
+	err := fn()
+	if !errors.Is(err, ErrSessionExpired) {
+		return err
+	}
+
+	cs.mu.RLock()
+	username, password := cs.username, cs.password
+	cs.mu.RUnlock()
+	if username == "" || password == "" {
+		return err
+	}
+
+	if err := cs.relogin(ctx, generation, username, password); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// relogin 重新登入。多個併發請求同時發現會話失效時，只有第一個會真正執行登入，
+// 其餘請求在等待後直接使用新的會話。
+func (cs *ClientSession) relogin(ctx context.Context, generation int, username, password string) error {
+	cs.loginMu.Lock()
+	defer cs.loginMu.Unlock()
+	if cs.loginGeneration() != generation {
+		return nil // 其他請求已經完成了重新登入
+	}
+
+	// 同一會話的重新登入已因帳號問題失敗時不再重試，避免併發請求連續提交錯誤密碼導致帳號被鎖定
+	if cs.reloginErr != nil && cs.reloginErrGen == generation {
+		return cs.reloginErr
+	}
+
+	cs.log().Warnf("會話已失效，正在重新登入...")
+	err := cs.Login(ctx, username, password)
+	if errors.Is(err, ErrBadCredentials) || errors.Is(err, ErrAccountLocked) || errors.Is(err, ErrCaptchaRequired) {
+		cs.reloginErr, cs.reloginErrGen = err, generation
+	}
+	return err
+}
+
+// loginGeneration 返回成功登入的次數，用於判斷會話是否已被其他請求更新
+func (cs *ClientSession) loginGeneration() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.generation
+}
+
+// baseURL 返回登入後門戶的基礎 URL
+func (cs *ClientSession) baseURL() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.reqURL
+}
+
+// setBaseURL 設定門戶的基礎 URL
+func (cs *ClientSession) setBaseURL(u string) {
+	cs.mu.Lock()
+	cs.reqURL = u
+	cs.mu.Unlock()
+}
+
+// postWidget 向只讀的 widgets 接口發送 JSON 請求，返回原始響應主體。
+// 這類接口只查詢數據，因此標記為可以安全重試。
+func (cs *ClientSession) postWidget(ctx context.Context, widget string, payload interface{}) ([]byte, error) {
+	requestURL, err := cs.widgetURL(widget)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling request body to JSON: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(httpretry.Idempotent(ctx), "POST", requestURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating POST request for %s: %w", widget, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", cs.UserAgent)
+
+	resp, err := cs.Client.Do(req)
+	if err != nil {
+		return nil, portalUnavailableError("Error sending POST request to "+widget, err)
+	}
+	defer resp.Body.Close()
+	if err := checkPortalStatus("Error sending POST request to "+widget, resp); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s response body: %w", widget, err)
+	}
+	if err := checkJSONResponse(resp, bodyBytes); err != nil {
+		return nil, err
+	}
+	return bodyBytes, nil
+}
@@ -0,0 +1,167 @@
+package sdtbu
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFetchWorkers 是 FetchSemester 未指定併發數時使用的工作協程數量
+const DefaultFetchWorkers = 4
+
+// Timetable 保存一個學期內每個教學週的課程，可以離線查詢任意日期的課程
+type Timetable struct {
+	Semester  Semester         `json:"semester"`        // 課表所屬的學期
+	Weeks     map[int][]Course `json:"weeks"`           // 教學週 -> 該週的課程 (已排序)
+	Exams     []Exam           `json:"exams,omitempty"` // 本學期的考試安排 (按開始時間排序)，FetchSemester 不獲取，導出日曆前由調用方從 ExamStore 填入
+	FetchedAt time.Time        `json:"fetchedAt"`       // 從門戶獲取的時間，用於判斷緩存是否過期
+}
+
+// CoursesInWeek 返回指定日期所在教學週實際上課的全部課程。
+// 門戶返回的課程不一定都在該週上課，這裡按課程的週次範圍與單雙週篩選。
+func (tt *Timetable) CoursesInWeek(date time.Time) []Course {
+	week := tt.Semester.WeekOf(date)
+	return coursesMeetingIn(tt.Weeks[week], week)
+}
+
+// CoursesOn 返回指定日期當天實際上課的課程，按節次排序。
+// 假期返回 nil；調休日返回所跟隨日期的課程。
+func (tt *Timetable) CoursesOn(date time.Time) []Course {
+	day := ScheduleOn(date)
+	if day.NoClass {
+		return nil
+	}
+	weekday := day.Weekday()
+	var courses []Course
+	for _, course := range tt.CoursesInWeek(day.Follows) {
+		if course.Weekday == weekday {
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
+
+// NextClass 在課表中查找下一節課程，最多向後查找 DefaultLookaheadDays 天，已考慮假期與調休。
+// 需要上課日期或按需獲取缺少的教學週時使用 NextOccurrence。
+func (tt *Timetable) NextClass() (*Course, error) {
+	occurrence, err := tt.NextOccurrence(context.Background(), now(), DefaultLookaheadDays, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &occurrence.Course, nil
+}
+
+// HasWeek 判斷課表中是否已包含指定教學週的數據
+func (tt *Timetable) HasWeek(week int) bool {
+	_, ok := tt.Weeks[week]
+	return ok
+}
+
+// FetchWeek 獲取並解析指定教學週的課程，返回排序後的課程列表
+func (cs *ClientSession) FetchWeek(ctx context.Context, semester *Semester, week int) ([]Course, error) {
+	var bodyBytes []byte
+	err := cs.withRelogin(ctx, func() error {
+		var err error
+		bodyBytes, err = cs.fetchClassbyWeek(ctx, semester, week)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	courses, err := cs.ParseClassList(string(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	sorted, _ := cs.SortClass(courses)
+	if sorted == nil {
+		sorted = []Course{} // 區分 "該週沒有課程" 與 "尚未獲取該週"
+	}
+	return sorted, nil
+}
+
+// FetchSemester 獲取當前學期所有教學週的課表。
+// 各週的請求由 workers 個工作協程併發執行 (workers <= 0 時使用 DefaultFetchWorkers)，
+// 任意一週失敗都會返回錯誤，以免把不完整的課表寫入緩存。
+func (cs *ClientSession) FetchSemester(ctx context.Context, workers int) (*Timetable, error) {
+	current := now()
+	semester, err := cs.ResolveSemester(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+	if cs.CalssListUserInfoString == "" {
+		if err := cs.GetClassbyUserInfo(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if workers <= 0 {
+		workers = DefaultFetchWorkers
+	}
+
+	cs.log().Infof("Fetching %d weeks of %s with %d workers...", semester.Weeks, semester, workers)
+
+	type weekResult struct {
+		week    int
+		courses []Course
+		err     error
+	}
+
+	jobs := make(chan int)
+	results := make(chan weekResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for week := range jobs {
+				courses, err := cs.FetchWeek(ctx, semester, week)
+				results <- weekResult{week: week, courses: courses, err: err}
+			}
+		}()
+	}
+	go func() {
+	produce:
+		for week := 1; week <= semester.Weeks; week++ {
+			select {
+			case jobs <- week:
+			case <-ctx.Done():
+				break produce // 已取消，不再派發新的週次
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	timetable := &Timetable{
+		Semester:  *semester,
+		Weeks:     make(map[int][]Course, semester.Weeks),
+		FetchedAt: current,
+	}
+	var failedWeeks []int
+	var firstErr error
+	for result := range results {
+		if result.err != nil {
+			failedWeeks = append(failedWeeks, result.week)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+		timetable.Weeks[result.week] = result.courses
+	}
+
+	if firstErr != nil {
+		sort.Ints(failedWeeks)
+		return nil, fmt.Errorf("獲取第 %v 週課表失敗: %w", failedWeeks, firstErr)
+	}
+	// 取消後未派發的週次不會出現在結果中，即使已派發的週次都成功也不能返回不完整的課表
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("獲取學期課表已取消: %w", err)
+	}
+	if len(timetable.Weeks) != semester.Weeks {
+		return nil, fmt.Errorf("只獲取了 %d/%d 週課表", len(timetable.Weeks), semester.Weeks)
+	}
+	return timetable, nil
+}
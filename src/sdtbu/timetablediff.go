@@ -0,0 +1,182 @@
+package sdtbu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangeKind 表示課表變化的類型
+type ChangeKind string
+
+const (
+	CourseAdded    ChangeKind = "added"   // 新增的課程
+	CourseRemoved  ChangeKind = "removed" // 取消的課程
+	CourseMoved    ChangeKind = "moved"   // 調課：上課日期或節次改變
+	RoomChanged    ChangeKind = "room"    // 換教室
+	TeacherChanged ChangeKind = "teacher" // 換教師
+)
+
+// TimetableChange 表示某一天某門課程的變化。同一門課程可以同時調課與換教室，
+// 因此 Kinds 可能包含多個類型；新增或取消的課程只包含一個類型。
+type TimetableChange struct {
+	Kinds   []ChangeKind
+	Week    int       // 變化前課程所在的教學週 (新增課程為變化後所在的週)
+	Date    time.Time // 受影響的日期：變化前的上課日期，新增課程為新的上課日期
+	NewDate time.Time // 變化後的上課日期，取消的課程為零值
+	Old     *Course   // 變化前的課程，新增的課程為 nil
+	New     *Course   // 變化後的課程，取消的課程為 nil
+}
+
+// Has 判斷變化是否包含指定類型
+func (c *TimetableChange) Has(kind ChangeKind) bool {
+	for _, k := range c.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary 返回不含日期的變化描述，每週重複的同一變化 (例如整學期換教室) 描述相同，可以據此合併。
+// 例如 "高等數學 教室 1號教學樓101 → 2號教學樓305"。
+func (c *TimetableChange) Summary() string {
+	switch {
+	case c.Has(CourseAdded):
+		return strings.TrimSpace(fmt.Sprintf("新增 %s %s %s %s", c.New.Name, weekdayLabel(c.New.Weekday), c.New.LessonLabel(), c.New.Location))
+	case c.Has(CourseRemoved):
+		return fmt.Sprintf("取消 %s %s %s", c.Old.Name, weekdayLabel(c.Old.Weekday), c.Old.LessonLabel())
+	}
+	parts := []string{c.Old.Name}
+	if c.Has(CourseMoved) {
+		parts = append(parts, fmt.Sprintf("調課 %s%s → %s%s", weekdayLabel(c.Old.Weekday), c.Old.LessonLabel(), weekdayLabel(c.New.Weekday), c.New.LessonLabel()))
+	}
+	if c.Has(RoomChanged) {
+		parts = append(parts, fmt.Sprintf("教室 %s → %s", placeholder(c.Old.Location), placeholder(c.New.Location)))
+	}
+	if c.Has(TeacherChanged) {
+		parts = append(parts, fmt.Sprintf("教師 %s → %s", placeholder(c.Old.Teacher), placeholder(c.New.Teacher)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DiffTimetables 逐日比較兩份課表，返回新增、取消、調課、換教室與換教師的課程，按日期與節次排序。
+// 只比較兩份課表都已獲取的教學週，並按週次範圍篩選出該週實際上課的課程；學期不同 (例如進入新學期) 時返回 nil。
+// 同名課程先在同一天內配對，剩餘的再在同一週內配對 (視為調到其他日期)，仍未配對的視為新增或取消。
+func DiffTimetables(old, new *Timetable) []TimetableChange {
+	if old == nil || new == nil || old.Semester.SchoolYear != new.Semester.SchoolYear ||
+		old.Semester.Term != new.Semester.Term || !old.Semester.StartDate.Equal(new.Semester.StartDate) {
+		return nil
+	}
+
+	var weeks []int
+	for week := range new.Weeks {
+		if old.HasWeek(week) {
+			weeks = append(weeks, week)
+		}
+	}
+	sort.Ints(weeks)
+
+	var changes []TimetableChange
+	for _, week := range weeks {
+		changes = append(changes, diffWeek(&new.Semester, week, coursesMeetingIn(old.Weeks[week], week), coursesMeetingIn(new.Weeks[week], week))...)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].Date.Equal(changes[j].Date) {
+			return changes[i].Date.Before(changes[j].Date)
+		}
+		return changes[i].lesson() < changes[j].lesson()
+	})
+	return changes
+}
+
+// diffWeek 比較同一教學週的兩份課程列表
+func diffWeek(semester *Semester, week int, oldCourses, newCourses []Course) []TimetableChange {
+	dateOf := func(c *Course) time.Time {
+		return semester.WeekStart(week).AddDate(0, 0, c.Weekday-1)
+	}
+	oldLeft := make([]*Course, 0, len(oldCourses))
+	for i := range oldCourses {
+		oldLeft = append(oldLeft, &oldCourses[i])
+	}
+	newLeft := make([]*Course, 0, len(newCourses))
+	for i := range newCourses {
+		newLeft = append(newLeft, &newCourses[i])
+	}
+
+	var changes []TimetableChange
+	// 依次使用越來越寬鬆的條件配對：完全相同的時間 -> 同一天 -> 同一週
+	matchers := []func(a, b *Course) bool{
+		func(a, b *Course) bool {
+			return a.Weekday == b.Weekday && a.StartLesson == b.StartLesson && a.EndLesson == b.EndLesson
+		},
+		func(a, b *Course) bool { return a.Weekday == b.Weekday },
+		func(a, b *Course) bool { return true },
+	}
+	for _, sameSlot := range matchers {
+		for i := 0; i < len(oldLeft); i++ {
+			for j := 0; j < len(newLeft); j++ {
+				a, b := oldLeft[i], newLeft[j]
+				if a.Name != b.Name || !sameSlot(a, b) {
+					continue
+				}
+				if kinds := courseChangeKinds(a, b); len(kinds) > 0 {
+					changes = append(changes, TimetableChange{Kinds: kinds, Week: week, Date: dateOf(a), NewDate: dateOf(b), Old: a, New: b})
+				}
+				oldLeft = append(oldLeft[:i], oldLeft[i+1:]...)
+				newLeft = append(newLeft[:j], newLeft[j+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	for _, c := range oldLeft {
+		changes = append(changes, TimetableChange{Kinds: []ChangeKind{CourseRemoved}, Week: week, Date: dateOf(c), Old: c})
+	}
+	for _, c := range newLeft {
+		changes = append(changes, TimetableChange{Kinds: []ChangeKind{CourseAdded}, Week: week, Date: dateOf(c), NewDate: dateOf(c), New: c})
+	}
+	return changes
+}
+
+// courseChangeKinds 返回同一門課程在兩份課表之間發生的變化
+func courseChangeKinds(a, b *Course) []ChangeKind {
+	var kinds []ChangeKind
+	if a.Weekday != b.Weekday || a.StartLesson != b.StartLesson || a.EndLesson != b.EndLesson {
+		kinds = append(kinds, CourseMoved)
+	}
+	if a.Location != b.Location {
+		kinds = append(kinds, RoomChanged)
+	}
+	if a.Teacher != b.Teacher {
+		kinds = append(kinds, TeacherChanged)
+	}
+	return kinds
+}
+
+// lesson 返回用於排序的節次
+func (c *TimetableChange) lesson() int {
+	if c.Old != nil {
+		return c.Old.StartLesson
+	}
+	return c.New.StartLesson
+}
+
+// weekdayLabel 返回星期的顯示文字，例如 "週一"
+func weekdayLabel(weekday int) string {
+	names := []string{"一", "二", "三", "四", "五", "六", "日"}
+	if weekday < 1 || weekday > 7 {
+		return "週?"
+	}
+	return "週" + names[weekday-1]
+}
+
+// placeholder 在值為空時返回 "未知"
+func placeholder(value string) string {
+	if value == "" {
+		return "未知"
+	}
+	return value
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	ASNIColor "CourseTool/asnicolor"
+	"CourseTool/clock"
+	"CourseTool/sdtbu"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// simulatedTimeLayout 是 -now 與 /simulate 接受的時刻格式
+const simulatedTimeLayout = "2006-01-02 15:04"
+
+// appClock 是排程器、推送重試、考試提醒與控制台命令使用的時鐘，-now 啟動時替換為從指定時刻開始流逝的時鐘。
+// 登入暫停、HTTP 請求重試與日誌時間戳始終使用系統時間。
+var appClock clock.Clock = clock.System{}
+
+// parseSimulatedTime 解析 "YYYY-MM-DD HH:MM" 格式的本地時刻
+func parseSimulatedTime(value string) (time.Time, error) {
+	at, err := time.ParseInLocation(simulatedTimeLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("無效的時刻 '%s'，預期格式為 YYYY-MM-DD HH:MM", value)
+	}
+	return at, nil
+}
+
+// setSimulatedNow 使程式從指定時刻開始運行，排程器、考試提醒與 sdtbu 的教學週計算都使用該時鐘
+func setSimulatedNow(value string) error {
+	at, err := parseSimulatedTime(value)
+	if err != nil {
+		return err
+	}
+	startClockAt(at)
+	logger().Info("模擬模式：程式將從指定時刻開始運行。", "now", at.Format(simulatedTimeLayout))
+	return nil
+}
+
+// startClockAt 將 appClock 與 sdtbu 的時鐘替換為從 at 開始流逝的時鐘
+func startClockAt(at time.Time) {
+	appClock = clock.NewOffset(at)
+	sdtbu.SetClock(appClock)
+}
+
+// simulateReminder 打印在 at 時刻觸發推送時將會發送的內容，不會真正發送推送
+func (a *account) simulateReminder(ctx context.Context, at time.Time) {
+	classInfo, err := a.fetchAndProcessClassData(ctx, at)
+	if err != nil {
+		fmt.Printf(ASNIColor.Red+"錯誤: 無法獲取課程資訊: %v\n"+ASNIColor.Reset, err)
+		return
+	}
+
+	var courseName, teacherName, location, timeNumber string
+	switch exam := a.nextExamBefore(ctx, classInfo, at); {
+	case exam != nil:
+		courseName, teacherName, location, timeNumber = extractExamInfo(exam)
+	case classInfo != nil:
+		courseName, teacherName, location, timeNumber = extractOccurrenceInfo(classInfo, at)
+	default:
+		fmt.Printf(ASNIColor.Yellow+"在 %s 觸發推送時沒有找到下一節課資訊，將跳過推送。\n"+ASNIColor.Reset, at.Format(simulatedTimeLayout))
+		return
+	}
+
+	fmt.Printf(ASNIColor.BrightYellow+"在 %s 觸發推送時將發送："+ASNIColor.Reset+"\n", at.Format("2006-01-02 Mon 15:04"))
+	fmt.Printf("課程名稱: %s\n", courseName)
+	fmt.Printf("教師姓名: %s\n", teacherName)
+	fmt.Printf("上課地點: %s\n", location)
+	fmt.Printf("上課節次: %s\n", timeNumber)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"CourseTool/sdtbu"
+	"CourseTool/wxpush"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxChangeDates 是變化摘要中每項變化最多列出的日期數量
+const maxChangeDates = 5
+
+// changeGroup 是描述相同的一組變化，例如整學期的同一次換教室
+type changeGroup struct {
+	summary string
+	dates   []time.Time
+}
+
+// groupTimetableChanges 合併描述相同的變化，只保留今天及以後受影響的日期，按首次出現的順序排列
+func groupTimetableChanges(changes []sdtbu.TimetableChange, now time.Time) []changeGroup {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var groups []changeGroup
+	index := make(map[string]int)
+	for i := range changes {
+		change := &changes[i]
+		date := change.Date
+		if change.NewDate.After(date) {
+			date = change.NewDate // 調到以後的課程仍需提醒
+		}
+		if date.Before(today) {
+			continue
+		}
+		summary := change.Summary()
+		n, ok := index[summary]
+		if !ok {
+			n = len(groups)
+			index[summary] = n
+			groups = append(groups, changeGroup{summary: summary})
+		}
+		groups[n].dates = append(groups[n].dates, change.Date)
+	}
+	return groups
+}
+
+// formatChangeDates 返回日期列表的顯示文字，例如 "10-20、10-27 等 12 天"
+func formatChangeDates(dates []time.Time) string {
+	labels := make([]string, 0, maxChangeDates)
+	for i, date := range dates {
+		if i == maxChangeDates {
+			break
+		}
+		labels = append(labels, date.Format("01-02"))
+	}
+	text := strings.Join(labels, "、")
+	if len(dates) > maxChangeDates {
+		text += fmt.Sprintf(" 等 %d 天", len(dates))
+	}
+	return text
+}
+
+// notifyTimetableChanges 打印課表變化並推送摘要。TIMETABLE_CHANGE_NOTIFY 設定為 off 時只打印不推送。
+func (a *account) notifyTimetableChanges(ctx context.Context, changes []sdtbu.TimetableChange) {
+	groups := groupTimetableChanges(changes, appClock.Now())
+	if len(groups) == 0 {
+		return // 只有已經過去的日期發生變化
+	}
+
+	summaries := make([]string, 0, len(groups))
+	a.logger.Info("課表發生了變化", "changes", len(groups))
+	for _, group := range groups {
+		line := fmt.Sprintf("%s (%s)", group.summary, formatChangeDates(group.dates))
+		summaries = append(summaries, line)
+		a.logger.Info("  " + line)
+	}
+
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("TIMETABLE_CHANGE_NOTIFY")), "off") {
+		return
+	}
+	var dates []time.Time
+	for _, group := range groups {
+		dates = append(dates, group.dates...)
+	}
+	a.sendNotice(ctx, wxpush.NoticeData{
+		Title:   fmt.Sprintf("【課表變更】共 %d 項", len(groups)),
+		Content: strings.Join(summaries, "；"),
+		Remark:  "涉及日期 " + formatChangeDates(uniqueSortedDates(dates)) + "，獲取於 " + appClock.Now().Format("01-02 15:04"),
+	})
+}
+
+// uniqueSortedDates 返回去重並按時間排序的日期
+func uniqueSortedDates(dates []time.Time) []time.Time {
+	seen := make(map[string]bool)
+	var unique []time.Time
+	for _, date := range dates {
+		key := date.Format("2006-01-02")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, date)
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Before(unique[j]) })
+	return unique
+}
@@ -2,9 +2,12 @@ package update // 將 package main 改為 package update
 
 import (
 	ASNIColor "CourseTool/asnicolor" // 新增：引入 ASNIColor 包
+	"CourseTool/httpclient"
+	"CourseTool/logging"
+	"context"
 	"fmt"
 	"io" // For io.Copy and io.ReadAll
-	"log"
+	"log/slog"
 	"net/http"
 	"os"            // For os.Create, os.Executable, os.Remove, os.Rename, os.Stat, os.ReadFile
 	"path/filepath" // For getting executable path
@@ -12,12 +15,21 @@ import (
 	"strconv"
 	"strings"
 	"sync" // 新增：用於 ProgressBarWriter 的互斥鎖
+	"time"
 )
 
 // CurrentAppVersion 定義當前應用程式的版本
 // 這個版本號應該與您 main.go 中橫幅顯示的版本一致
 const CurrentAppVersion = "1.0.0"
 
+// DownloadTimeout 是下載新版本的超時時間，獲取遠端版本號使用 httpclient 設定的請求超時
+var DownloadTimeout = 10 * time.Minute
+
+// logger 返回 update 的日誌輸出
+func logger() *slog.Logger {
+	return logging.Component("update")
+}
+
 // ProgressBarWriter 是一個 io.Writer，用於顯示下載進度條
 type ProgressBarWriter struct {
 	writer       io.Writer  // 底層的文件寫入器
@@ -99,8 +111,12 @@ func byteCountToHuman(b int64) string {
 }
 
 // getRemoteVersion 從指定的 URL 獲取遠端版本號
-func getRemoteVersion(url string) (string, error) {
-	resp, err := http.Get(url)
+func getRemoteVersion(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("創建版本請求失敗: %v", err)
+	}
+	resp, err := httpclient.New().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("獲取遠端版本失敗: %v", err)
 	}
@@ -175,14 +191,24 @@ func compareVersions(versionA, versionB string) (int, error) {
 }
 
 // downloadFile 下載文件到指定路徑
-func downloadFile(filepath string, url string) error {
+func downloadFile(ctx context.Context, filepath string, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, DownloadTimeout)
+	defer cancel()
+
 	out, err := os.Create(filepath)
 	if err != nil {
 		return fmt.Errorf("創建文件失敗: %v", err)
 	}
 	defer out.Close()
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("創建下載請求失敗: %v", err)
+	}
+	// 下載時間可能遠超單個請求的超時，只受 DownloadTimeout 限制
+	client := httpclient.New()
+	client.Timeout = 0
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("下載文件失敗: %v", err)
 	}
@@ -214,37 +240,36 @@ func downloadFile(filepath string, url string) error {
 }
 
 // CheckForUpdates 檢查是否有新的應用程式版本可用並在 Windows 上執行更新
-func CheckForUpdates() {
+func CheckForUpdates(ctx context.Context) {
 	remoteVersionURL := "https://coursetool.ric.moe/CTversion"          // 遠端版本資訊的 URL
 	downloadURL := "https://software.ric.moe/CourseTool/CourseTool.exe" // Windows 更新下載 URL
 
-	log.Printf("正在檢查更新... 當前版本: %s\n", CurrentAppVersion)
+	logger().Info("正在檢查更新...", "version", CurrentAppVersion)
 
-	remoteVersion, err := getRemoteVersion(remoteVersionURL)
+	remoteVersion, err := getRemoteVersion(ctx, remoteVersionURL)
 	if err != nil {
-		log.Printf("檢查更新失敗: %v\n", err)
+		logger().Warn("檢查更新失敗", "err", err)
 		return
 	}
 
-	log.Printf("遠端最新版本: %s\n", remoteVersion)
+	logger().Info("遠端最新版本", "version", remoteVersion)
 
 	comparison, err := compareVersions(CurrentAppVersion, remoteVersion)
 	if err != nil {
-		log.Printf("比較版本失敗: %v\n", err)
+		logger().Warn("比較版本失敗", "err", err)
 		return
 	}
 
 	if comparison == -1 {
-		// 使用 ASNIColor 進行輸出
-		fmt.Printf("***** %s有新版本可用！*****\n", ASNIColor.BrightGreen+"更新提醒"+ASNIColor.Reset)
+		logger().Info("有新版本可用！", "version", remoteVersion)
 
 		if runtime.GOOS == "windows" {
-			fmt.Printf("檢測到 Windows 系統，正在嘗試自動更新...\n")
+			logger().Info("檢測到 Windows 系統，正在嘗試自動更新...")
 
 			// 獲取當前執行檔的路徑
 			exePath, err := os.Executable() // os.Executable 已經是推薦的替代方案
 			if err != nil {
-				log.Printf("獲取當前執行檔路徑失敗: %v\n", err)
+				logger().Error("獲取當前執行檔路徑失敗", "err", err)
 				return
 			}
 			exeDir := filepath.Dir(exePath)
@@ -253,57 +278,57 @@ func CheckForUpdates() {
 			tempFileName := filepath.Join(exeDir, exeName+".new")
 			oldFileName := filepath.Join(exeDir, exeName+".old")
 
-			fmt.Printf("正在下載新版本到: %s\n", tempFileName)
-			err = downloadFile(tempFileName, downloadURL)
+			logger().Info("正在下載新版本", "path", tempFileName)
+			err = downloadFile(ctx, tempFileName, downloadURL)
 			if err != nil {
-				log.Printf("下載新版本失敗: %v\n", err)
+				logger().Error("下載新版本失敗", "err", err)
 				return
 			}
-			fmt.Printf("新版本下載完成。\n")
+			logger().Info("新版本下載完成。")
 
 			// 嘗試將舊的執行檔重命名
 			// 如果 oldFileName 已經存在，先刪除它（可能是上次更新失敗留下的）
 			if _, err := os.Stat(oldFileName); err == nil { // os.Stat 已經是推薦的替代方案
 				if err := os.Remove(oldFileName); err != nil { // os.Remove 已經是推薦的替代方案
-					log.Printf("刪除舊的備份執行檔失敗: %v\n", err)
+					logger().Warn("刪除舊的備份執行檔失敗", "err", err)
 					// 不返回，嘗試繼續
 				}
 			}
 
-			fmt.Printf("正在備份舊版本 (%s) 到 (%s)...\n", exePath, oldFileName)
+			logger().Info("正在備份舊版本...", "from", exePath, "to", oldFileName)
 			err = os.Rename(exePath, oldFileName) // os.Rename 已經是推薦的替代方案
 			if err != nil {
-				log.Printf("備份舊版本失敗: %v\n", err)
+				logger().Error("備份舊版本失敗", "err", err)
 				// 如果備份失敗，嘗試清理下載的新文件
 				os.Remove(tempFileName)
 				return
 			}
-			fmt.Printf("舊版本備份完成。\n")
+			logger().Info("舊版本備份完成。")
 
 			// 將新下載的文件重命名為當前執行檔名稱
-			fmt.Printf("正在用新版本覆蓋舊版本...\n")
+			logger().Info("正在用新版本覆蓋舊版本...")
 			err = os.Rename(tempFileName, exePath) // os.Rename 已經是推薦的替代方案
 			if err != nil {
-				log.Printf("覆蓋舊版本失敗: %v\n", err)
+				logger().Error("覆蓋舊版本失敗", "err", err)
 				// 如果覆蓋失敗，嘗試恢復舊版本
 				if err := os.Rename(oldFileName, exePath); err != nil {
-					log.Printf("恢復舊版本失敗: %v\n", err)
+					logger().Error("恢復舊版本失敗", "err", err)
 				}
 				return
 			}
-			fmt.Printf("%s更新成功！請重新啟動程式以應用新版本。\n", ASNIColor.BrightGreen+"自動更新"+ASNIColor.Reset)
+			logger().Info("自動更新成功！請重新啟動程式以應用新版本。")
 
 			// 嘗試刪除舊的備份文件
 			if err := os.Remove(oldFileName); err != nil { // os.Remove 已經是推薦的替代方案
-				log.Printf("刪除舊版本備份文件失敗: %v\n", err)
+				logger().Warn("刪除舊版本備份文件失敗", "err", err)
 			}
 
 		} else {
-			fmt.Printf("請訪問 %s 下載最新版本並手動更新。\n", "https://github.com/RichardMiku/CourseTool")
+			logger().Info("請訪問 https://github.com/RichardMiku/CourseTool 下載最新版本並手動更新。")
 		}
 	} else if comparison == 1 {
-		fmt.Printf("%s 您當前版本 (%s) 比遠端版本 (%s) 更新。這可能是開發版本或錯誤。\n", ASNIColor.Yellow+"警告:"+ASNIColor.Reset, CurrentAppVersion, remoteVersion)
+		logger().Warn("您當前版本比遠端版本更新。這可能是開發版本或錯誤。", "version", CurrentAppVersion, "remote", remoteVersion)
 	} else {
-		fmt.Printf("%s 您當前版本是最新的 (%s)。\n", ASNIColor.BrightGreen+"已是最新版本"+ASNIColor.Reset, CurrentAppVersion)
+		logger().Info("已是最新版本。", "version", CurrentAppVersion)
 	}
 }
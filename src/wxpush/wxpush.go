@@ -1,22 +1,31 @@
 package wxpush
 
 import (
+	"CourseTool/httpclient"
+	"CourseTool/logging"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 )
 
+// logger 返回 wxpush 的日誌輸出
+func logger() *slog.Logger {
+	return logging.Component("wxpush")
+}
+
 // 微信配置變數，將從環境變數載入
 var (
 	appID            string
 	appSecret        string
 	openID           string
 	courseTemplateID string
+	noticeTemplateID string
 )
 
 func init() {
@@ -24,23 +33,24 @@ func init() {
 	appSecret = os.Getenv("WXPUSH_APP_SECRET")
 	openID = os.Getenv("WXPUSH_OPEN_ID")
 	courseTemplateID = os.Getenv("WXPUSH_COURSE_TEMPLATE_ID")
+	noticeTemplateID = os.Getenv("WXPUSH_NOTICE_TEMPLATE_ID")
 
 	// 檢查是否設定了所有必要的環境變數
 	if appID == "" {
-		log.Printf("WXPUSH 警告: 環境變數 WXPUSH_APP_ID 未設定。")
+		logger().Warn("環境變數 WXPUSH_APP_ID 未設定。")
 	}
 	if appSecret == "" {
-		log.Printf("WXPUSH 警告: 環境變數 WXPUSH_APP_SECRET 未設定。")
+		logger().Warn("環境變數 WXPUSH_APP_SECRET 未設定。")
 	}
 	if openID == "" {
-		log.Printf("WXPUSH 警告: 環境變數 WXPUSH_OPEN_ID 未設定。")
+		logger().Warn("環境變數 WXPUSH_OPEN_ID 未設定 (使用 ACCOUNTS_FILE 時在各帳號的 openIds 中設定接收者)。")
 	}
 	if courseTemplateID == "" {
-		log.Printf("WXPUSH 警告: 環境變數 WXPUSH_COURSE_TEMPLATE_ID 未設定。")
+		logger().Warn("環境變數 WXPUSH_COURSE_TEMPLATE_ID 未設定。")
 	}
 
 	if appID == "" || appSecret == "" || openID == "" || courseTemplateID == "" {
-		log.Printf("WXPUSH 警告: 一個或多個 WXPUSH 環境變數未設定，相關功能可能無法正常工作。")
+		logger().Warn("一個或多個 WXPUSH 環境變數未設定，相關功能可能無法正常工作。")
 	}
 }
 
@@ -59,24 +69,41 @@ type SendMessageResponse struct {
 	MsgID   int64  `json:"msgid"`
 }
 
+// doRequest 發送請求並讀取完整回應。請求使用 httpclient 的共用設定，在網絡錯誤、429 與 5xx 時重試，
+// 微信接口持續失敗時熔斷；發送模板消息的 POST 不是冪等的，只會在請求確定未被處理時 (連接失敗、429、503) 重試。
+func doRequest(ctx context.Context, method, url, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("創建請求失敗: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := httpclient.New().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("發送請求失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 使用 io.ReadAll 替換 ioutil.ReadAll
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("讀取回應失敗: %w", err)
+	}
+	return respBody, nil
+}
+
 // GetAccessToken 函式用於獲取微信公眾號的 access_token
-func GetAccessToken() (string, error) {
+func GetAccessToken(ctx context.Context) (string, error) {
 	// 在這裡再次檢查，確保在使用前變數已設定
 	if appID == "" || appSecret == "" {
 		return "", fmt.Errorf("獲取 access_token 失敗: WXPUSH_APP_ID 或 WXPUSH_APP_SECRET 未設定。")
 	}
 	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s", appID, appSecret)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("發送請求失敗: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 使用 io.ReadAll 替換 ioutil.ReadAll
-	body, err := io.ReadAll(resp.Body)
+	body, err := doRequest(ctx, "GET", url, "", nil)
 	if err != nil {
-		return "", fmt.Errorf("讀取回應失敗: %w", err)
+		return "", err
 	}
 
 	var result AccessTokenResponse
@@ -99,11 +126,14 @@ type CourseReminderData struct {
 	CourseName     string
 	TeacherName    string
 	CourseLocation string
-	TimeNumber     string // 例如 "第一節", "下午2點"
-	NowTime        string // 當前時間，用於顯示提醒發送時間
-	Note           string // 額外備註或每日一句
+	TimeNumber     string    // 例如 "第一節", "下午2點"
+	NowTime        time.Time // 提醒發送時間，由調用方傳入程式時鐘的當前時間，使 -now 與 /simulate 下顯示模擬的時刻
+	Note           string    // 額外備註或每日一句
 }
 
+// nowTimeLayout 是模板消息中發送時間的顯示格式
+const nowTimeLayout = "2006年01月02日 15:04"
+
 // TemplateDataValue 結構用於模板消息中的數據值
 type TemplateDataValue struct {
 	Value string `json:"value"`
@@ -120,68 +150,132 @@ type TemplateData struct {
 	TodayNote      TemplateDataValue `json:"today_note"` // 額外備註
 }
 
+// NoticeData 結構用於傳遞通用通知，成績與課表變更等不屬於課程提醒的推送使用通知模板
+type NoticeData struct {
+	Title   string    // 通知標題，例如 "【成績】高等數學"
+	Content string    // 通知內容
+	Remark  string    // 補充說明，例如學期或獲取時間
+	NowTime time.Time // 通知發送時間，由調用方傳入程式時鐘的當前時間
+}
+
+// NoticeTemplateData 結構用於通知模板消息的數據部分
+type NoticeTemplateData struct {
+	Title   TemplateDataValue `json:"title"`
+	Content TemplateDataValue `json:"content"`
+	Remark  TemplateDataValue `json:"remark"`
+	Nowtime TemplateDataValue `json:"nowtime"` // 當前時間
+}
+
 // TemplateMessage 結構用於發送模板消息的請求體
 type TemplateMessage struct {
-	ToUser     string       `json:"touser"`
-	TemplateID string       `json:"template_id"`
-	URL        string       `json:"url"`
-	Data       TemplateData `json:"data"`
+	ToUser     string      `json:"touser"`
+	TemplateID string      `json:"template_id"`
+	URL        string      `json:"url"`
+	Data       interface{} `json:"data"` // TemplateData 或 NoticeTemplateData
+}
+
+// Recipient 是模板消息的接收者
+type Recipient struct {
+	OpenID           string // 接收者的 OpenID
+	TemplateID       string // 課程提醒模板 ID，為空時使用 WXPUSH_COURSE_TEMPLATE_ID
+	NoticeTemplateID string // 通知模板 ID，為空時使用 WXPUSH_NOTICE_TEMPLATE_ID
+}
+
+// HasCourseTemplate 返回是否可以向該接收者發送課程提醒
+func (r Recipient) HasCourseTemplate() bool {
+	return r.TemplateID != "" || courseTemplateID != ""
+}
+
+// HasNoticeTemplate 返回是否可以向該接收者發送通知
+func (r Recipient) HasNoticeTemplate() bool {
+	return r.NoticeTemplateID != "" || noticeTemplateID != ""
+}
+
+// DefaultRecipient 返回環境變數 WXPUSH_OPEN_ID 指定的接收者
+func DefaultRecipient() Recipient {
+	return Recipient{OpenID: openID}
 }
 
-// SendCourseReminder 函式用於發送課程提醒模板消息
-func SendCourseReminder(accessToken string, data CourseReminderData) error {
+// SendCourseReminder 函式用於向 WXPUSH_OPEN_ID 發送課程提醒模板消息
+func SendCourseReminder(ctx context.Context, accessToken string, data CourseReminderData) error {
+	return SendCourseReminderTo(ctx, accessToken, DefaultRecipient(), data)
+}
+
+// SendCourseReminderTo 向指定接收者發送課程提醒模板消息
+func SendCourseReminderTo(ctx context.Context, accessToken string, to Recipient, data CourseReminderData) error {
+	templateID := to.TemplateID
+	if templateID == "" {
+		templateID = courseTemplateID
+	}
 	// 在這裡再次檢查，確保在使用前變數已設定
-	if openID == "" || courseTemplateID == "" {
-		return fmt.Errorf("發送課程提醒失敗: WXPUSH_OPEN_ID 或 WXPUSH_COURSE_TEMPLATE_ID 未設定。")
+	if to.OpenID == "" || templateID == "" {
+		return fmt.Errorf("發送課程提醒失敗: 接收者的 OpenID 或 WXPUSH_COURSE_TEMPLATE_ID 未設定。")
 	}
 
-	// 獲取當前時間，用於 NowTime 欄位
-	currentTime := time.Now().Format("2006年01月02日 15:04")
-
 	message := TemplateMessage{
-		ToUser:     openID,
-		TemplateID: courseTemplateID,      // 使用新的課程提醒模板ID
+		ToUser:     to.OpenID,
+		TemplateID: templateID,            // 使用新的課程提醒模板ID
 		URL:        "https://www.ric.moe", // 可以替換為課程相關的連結
 		Data: TemplateData{
 			Coursename:     TemplateDataValue{Value: data.CourseName},
 			Teachername:    TemplateDataValue{Value: data.TeacherName},
 			Courselocation: TemplateDataValue{Value: data.CourseLocation},
 			Timenumber:     TemplateDataValue{Value: data.TimeNumber},
-			Nowtime:        TemplateDataValue{Value: currentTime},
+			Nowtime:        TemplateDataValue{Value: data.NowTime.Format(nowTimeLayout)},
 			TodayNote:      TemplateDataValue{Value: data.Note},
 		},
 	}
 
+	return sendTemplateMessage(ctx, accessToken, message, "課程提醒")
+}
+
+// SendNoticeTo 向指定接收者發送通知模板消息
+func SendNoticeTo(ctx context.Context, accessToken string, to Recipient, data NoticeData) error {
+	templateID := to.NoticeTemplateID
+	if templateID == "" {
+		templateID = noticeTemplateID
+	}
+	if to.OpenID == "" || templateID == "" {
+		return fmt.Errorf("發送通知失敗: 接收者的 OpenID 或 WXPUSH_NOTICE_TEMPLATE_ID 未設定。")
+	}
+
+	message := TemplateMessage{
+		ToUser:     to.OpenID,
+		TemplateID: templateID,
+		URL:        "https://www.ric.moe",
+		Data: NoticeTemplateData{
+			Title:   TemplateDataValue{Value: data.Title},
+			Content: TemplateDataValue{Value: data.Content},
+			Remark:  TemplateDataValue{Value: data.Remark},
+			Nowtime: TemplateDataValue{Value: data.NowTime.Format(nowTimeLayout)},
+		},
+	}
+	return sendTemplateMessage(ctx, accessToken, message, "通知")
+}
+
+// sendTemplateMessage 發送模板消息並檢查微信的回應，kind 用於錯誤訊息，例如 "課程提醒"
+func sendTemplateMessage(ctx context.Context, accessToken string, message TemplateMessage, kind string) error {
 	jsonBody, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("序列化請求體失敗: %w", err)
 	}
 
 	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=%s", accessToken)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return fmt.Errorf("發送請求失敗: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := doRequest(ctx, "POST", url, "application/json", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("讀取回應失敗: %w", err)
+		return err
 	}
 
 	// 解析微信伺服器的回應
 	var sendResp SendMessageResponse
 	if err := json.Unmarshal(body, &sendResp); err != nil {
-		// 如果解析失敗，仍然打印原始 body 並返回錯誤
-		fmt.Printf("發送課程提醒回應 (解析失敗): %s\n", string(body))
-		return fmt.Errorf("解析發送課程提醒回應失敗: %w, 原始回應: %s", err, string(body))
+		return fmt.Errorf("解析發送%s回應失敗: %w, 原始回應: %s", kind, err, string(body))
 	}
 
 	if sendResp.Errcode == 0 {
-		fmt.Printf("課程提醒發送成功! msgid: %d\n", sendResp.MsgID)
+		logger().Debug(kind+"發送成功", "openid", message.ToUser, "msgid", sendResp.MsgID)
 	} else {
-		fmt.Printf("課程提醒發送失敗! 錯誤碼: %d, 錯誤訊息: %s\n", sendResp.Errcode, sendResp.Errmsg)
-		return fmt.Errorf("發送課程提醒失敗，錯誤碼: %d, 錯誤訊息: %s", sendResp.Errcode, sendResp.Errmsg)
+		return fmt.Errorf("發送%s失敗，錯誤碼: %d, 錯誤訊息: %s", kind, sendResp.Errcode, sendResp.Errmsg)
 	}
 
 	return nil